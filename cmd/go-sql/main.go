@@ -0,0 +1,139 @@
+// Command go-sql runs the migrations and statements of a database configured with the flags package.
+//
+// The database drivers are behind build tags, ie.
+//
+//	go build -tags postgres,mysql,sqlite ./cmd/go-sql
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	sql "github.com/joematpal/go-sql/v2"
+	"github.com/joematpal/go-sql/v2/flags"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	debugFlag  = "debug"
+	dryRunFlag = "dry-run"
+)
+
+func main() {
+	app := &cli.App{
+		Name:  "go-sql",
+		Usage: "migrate and query the databases supported by go-sql",
+		Flags: append(append(append([]cli.Flag{
+			&cli.BoolFlag{
+				Name:  debugFlag,
+				Usage: "print the debug logs of go-sql",
+			},
+		}, flags.DBFlags...), flags.AWSCQLAuthFlags...), flags.CQLPolicyFlags...),
+		Commands: []*cli.Command{
+			migrateCommand(),
+			pingCommand(),
+			execCommand(),
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// newDB connects to the database of the flags, the migrations are only run by the migrate commands
+func newDB(c *cli.Context) (*sql.DB, error) {
+	opts := append(flags.DBOptions(c), sql.WithMigrate(false), sql.WithMigrateDryRunOutput(c.App.Writer))
+	// up --dry-run is the same as the global migrate-dry-run flag
+	if c.Bool(dryRunFlag) {
+		opts = append(opts, sql.WithMigrateDryRun(true))
+	}
+	if c.Bool(debugFlag) {
+		opts = append(opts, sql.WithDebugger(logger{}))
+	}
+	db, err := sql.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("new db: %w", err)
+	}
+	return db, nil
+}
+
+func pingCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "ping",
+		Usage: "check that the database can be reached",
+		Action: func(c *cli.Context) error {
+			db, err := newDB(c)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			if err := db.PingContext(c.Context); err != nil {
+				return fmt.Errorf("ping: %w", err)
+			}
+			fmt.Fprintf(c.App.Writer, "%s %s: ok\n", db.DBSource, db.DBName)
+			return nil
+		},
+	}
+}
+
+func execCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "exec",
+		Usage: "run the statements of a file, separated by ;",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "file",
+				Aliases:  []string{"f"},
+				Usage:    "the file with the statements, - reads stdin",
+				Required: true,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			script, err := readFile(c.String("file"))
+			if err != nil {
+				return err
+			}
+
+			db, err := newDB(c)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			return execScript(c.Context, db, script)
+		},
+	}
+}
+
+func execScript(ctx context.Context, db *sql.DB, script string) error {
+	stmts, err := sql.SplitStatements(db.DBSource, script)
+	if err != nil {
+		return fmt.Errorf("split statements: %w", err)
+	}
+	for i, stmt := range stmts {
+		if err := db.ExecStmtContext(ctx, stmt); err != nil {
+			return fmt.Errorf("statement %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func readFile(name string) (string, error) {
+	if name == "-" {
+		b, err := io.ReadAll(os.Stdin)
+		return string(b), err
+	}
+	b, err := os.ReadFile(name)
+	return string(b), err
+}
+
+type logger struct{}
+
+func (logger) Debugf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
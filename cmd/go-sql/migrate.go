@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	sql "github.com/joematpal/go-sql/v2"
+	"github.com/joematpal/go-sql/v2/flags"
+	"github.com/joematpal/go-sql/v2/schema"
+	"github.com/urfave/cli/v2"
+)
+
+func migrateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "run the migrations of migrate-path",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "up",
+				Usage:     "apply the pending migrations, or only the next N",
+				ArgsUsage: "[N]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  dryRunFlag,
+						Usage: "print the plan instead of applying it, the same as migrate-dry-run",
+					},
+				},
+				Action: withMigrator(func(c *cli.Context, m *sql.Migrator) error {
+					if c.Args().Present() {
+						n, err := positiveArg(c)
+						if err != nil {
+							return err
+						}
+						return m.Steps(n)
+					}
+					return m.Up()
+				}),
+			},
+			{
+				Name:      "down",
+				Usage:     "revert the last N migrations, or all of them with --all",
+				ArgsUsage: "[N]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "revert every migration",
+					},
+				},
+				Action: withMigrator(func(c *cli.Context, m *sql.Migrator) error {
+					if c.Bool("all") {
+						return m.Down()
+					}
+					if !c.Args().Present() {
+						return errors.New("pass the number of migrations to revert or --all")
+					}
+					n, err := positiveArg(c)
+					if err != nil {
+						return err
+					}
+					return m.Steps(-n)
+				}),
+			},
+			{
+				Name:  "status",
+				Usage: "print the current version and the pending migrations",
+				Action: withMigrator(func(c *cli.Context, m *sql.Migrator) error {
+					version, dirty, err := m.Version()
+					switch {
+					case errors.Is(err, sql.ErrNilVersion):
+						fmt.Fprintln(c.App.Writer, "version: none")
+					case err != nil:
+						return err
+					case dirty:
+						fmt.Fprintf(c.App.Writer, "version: %d (dirty)\n", version)
+					default:
+						fmt.Fprintf(c.App.Writer, "version: %d\n", version)
+					}
+
+					pending, err := m.Pending()
+					if err != nil {
+						return err
+					}
+					fmt.Fprintf(c.App.Writer, "pending: %v\n", pending)
+					return nil
+				}),
+			},
+			{
+				Name:  "plan",
+				Usage: "print the pending migrations with their checksums without applying them",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "print the plan with the contents of the migrations as json",
+					},
+				},
+				Action: withMigrator(printPlan),
+			},
+			{
+				Name:      "force",
+				Usage:     "set the version without running a migration and clear the dirty flag, -1 for none",
+				ArgsUsage: "VERSION",
+				Action: withMigrator(func(c *cli.Context, m *sql.Migrator) error {
+					version, err := strconv.Atoi(c.Args().First())
+					if err != nil || version < -1 {
+						return fmt.Errorf("invalid version %q", c.Args().First())
+					}
+					return m.Force(version)
+				}),
+			},
+			{
+				Name:      "create",
+				Usage:     "create the next numbered up and down files in migrate-path",
+				ArgsUsage: "NAME",
+				Action:    createMigration,
+			},
+		},
+	}
+}
+
+func printPlan(c *cli.Context, m *sql.Migrator) error {
+	plan, err := m.Plan()
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		enc := json.NewEncoder(c.App.Writer)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+	_, err = fmt.Fprint(c.App.Writer, plan)
+	return err
+}
+
+// withMigrator connects to the database and closes it after the action
+func withMigrator(action func(*cli.Context, *sql.Migrator) error) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		db, err := newDB(c)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		m, err := db.Migrator()
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+
+		return action(c, m)
+	}
+}
+
+// createMigration does not connect to the database, the extension only depends on the db source
+func createMigration(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return errors.New("pass the name of the migration")
+	}
+	source := flags.Source(c)
+	dir := (&sql.DB{
+		DBSource:           source,
+		MigratePath:        c.String(flags.MigratePath),
+		MigrateDialectDirs: c.Bool(flags.MigrateDialectDirs),
+	}).MigrateDir()
+
+	version, width, err := schema.NextVersion(os.DirFS(dir))
+	if err != nil {
+		return fmt.Errorf("next version: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	up, down := schema.MigrationFileNames(source.Dialect(), version, width, name)
+	for _, file := range []string{up, down} {
+		path := filepath.Join(dir, file)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintln(c.App.Writer, path)
+	}
+	return nil
+}
+
+func positiveArg(c *cli.Context) (int, error) {
+	n, err := strconv.Atoi(c.Args().First())
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid number of migrations %q", c.Args().First())
+	}
+	return n, nil
+}
@@ -1,102 +1,268 @@
 package sql
 
 import (
-	"errors"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"strconv"
-	"strings"
 	"sync"
+	"time"
 
 	"github.com/gocql/gocql"
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database"
-	"github.com/golang-migrate/migrate/v4/database/cassandra"
-	"github.com/golang-migrate/migrate/v4/database/mysql"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
-	"github.com/golang-migrate/migrate/v4/database/sqlite"
-	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/jmoiron/sqlx"
 	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/joematpal/go-sql/v2/table"
 	cqlreflectx "github.com/scylladb/go-reflectx"
 	"github.com/scylladb/gocqlx/v2"
 )
 
-// TODO: Use master v4 version
-// Current one is out of date and not supported
-var dbs = &dbConnections{
-	m: map[string]*DB{},
+// defaultRegistry is shared by every DB created without WithRegistry
+var defaultRegistry = NewRegistry()
+
+// Registry shares the connections of DBs with the same config. The DBs get a reference to the connection
+// and it is only closed when the last of them is closed. Connections that fail to open or migrate are not kept.
+type Registry struct {
+	mu sync.Mutex
+	m  map[string]*registryEntry
+	// secret keys the hmac of the credentials in the fingerprints
+	secret []byte
 }
 
-type dbConnections struct {
-	sync.Mutex
-	m map[string]*DB
+type registryEntry struct {
+	refs  int
+	ready chan struct{}
+	err   error
+	sql   *sqlx.DB
+	cql   *gocqlx.Session
+	stmts *stmtCache
 }
 
-func deleteDB(db string) {
-	dbs.Lock()
-	defer dbs.Unlock()
-	delete(dbs.m, db)
+// NewRegistry returns an empty Registry
+func NewRegistry() *Registry {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(fmt.Sprintf("sql: registry secret: %v", err))
+	}
+	return &Registry{
+		m:      map[string]*registryEntry{},
+		secret: secret,
+	}
 }
 
-func (dbc *dbConnections) GetSQLConnection(o *DB) error {
-	dbc.Lock()
-	defer dbc.Unlock()
+// Len returns the number of open connections
+func (r *Registry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.m)
+}
 
-	dbSource, err := o.getDataSource()
+// connectionFingerprint are the fields of the config that change the connection, without the secrets
+type connectionFingerprint struct {
+	DBSource                 DBSource          `json:"dbSource"`
+	Hosts                    []string          `json:"hosts"`
+	Port                     string            `json:"port"`
+	User                     string            `json:"user"`
+	DBName                   string            `json:"dbName"`
+	RawQuery                 string            `json:"rawQuery"`
+	CaPath                   string            `json:"caPath"`
+	TLS                      TLSConfig         `json:"tls"`
+	Timeout                  time.Duration     `json:"timeout"`
+	ConnectTimeout           time.Duration     `json:"connectTimeout"`
+	DisableInitialHostLookup bool              `json:"disableInitialHostLookup"`
+	Consistency              gocql.Consistency `json:"consistency"`
+	StmtCacheSize            int               `json:"stmtCacheSize"`
+	Pool                     PoolConfig        `json:"pool"`
+	CQLPolicy                CQLPolicyConfig   `json:"cqlPolicy"`
+	// Migrations are run when the connection is opened, so a DB with other migrations needs its own
+	Migrate    bool   `json:"migrate"`
+	MigrateDir string `json:"migrateDir"`
+	MigrateFS  string `json:"migrateFS"`
+	DryRun     bool   `json:"dryRun"`
+	// Credentials is the hmac of the password and the authenticator
+	Credentials string `json:"credentials"`
+}
+
+// identity of a map or a pointer is its address and of other values their type and value
+func identity(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Ptr, reflect.Slice, reflect.Chan, reflect.UnsafePointer:
+		return fmt.Sprintf("%T@%x", v, rv.Pointer())
+	}
+	return fmt.Sprintf("%T:%v", v, v)
+}
+
+// fingerprint keys the connection of the DB, it can be logged as it holds no secret
+func (r *Registry) fingerprint(o *DB) (string, error) {
+	mac := hmac.New(sha256.New, r.secret)
+	mac.Write([]byte(o.Password))
+	if o.Authenticator != nil {
+		fmt.Fprintf(mac, "%T%+v", o.Authenticator, o.Authenticator)
+	}
+
+	b, err := json.Marshal(connectionFingerprint{
+		DBSource:                 o.DBSource,
+		Hosts:                    o.Hosts,
+		Port:                     o.Port,
+		User:                     o.User,
+		DBName:                   o.DBName,
+		RawQuery:                 o.RawQuery,
+		CaPath:                   o.CaPath,
+		TLS:                      o.TLS,
+		Timeout:                  o.Timeout,
+		ConnectTimeout:           o.ConnectTimeout,
+		DisableInitialHostLookup: o.DisableInitialHostLookup,
+		Consistency:              o.Consistency,
+		StmtCacheSize:            o.StmtCacheSize,
+		Pool:                     o.Pool,
+		CQLPolicy:                o.CQLPolicy,
+		Migrate:                  o.Migrate,
+		MigrateDir:               o.MigrateDir(),
+		MigrateFS:                identity(o.MigrateFS),
+		DryRun:                   o.MigrateDryRun,
+		Credentials:              hex.EncodeToString(mac.Sum(nil)),
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return o.DBSource.String() + ":" + hex.EncodeToString(sum[:16]), nil
+}
+
+// acquire gives the DB a reference to the connection of its config, open is only called by the first DB
+// and the others wait for it
+func (r *Registry) acquire(o *DB, open func(*DB) error) error {
+	key, err := r.fingerprint(o)
 	if err != nil {
 		return err
 	}
 
-	o.Debugf("source %s: %s", o.DBSource, dbSource)
+	r.mu.Lock()
+	e, ok := r.m[key]
+	if !ok {
+		e = &registryEntry{ready: make(chan struct{})}
+		r.m[key] = e
+	}
+	e.refs++
+	r.mu.Unlock()
+
+	if ok {
+		o.Debugf("connection %s: shared", key)
+		<-e.ready
+		if e.err != nil {
+			return e.err
+		}
+		o.sql, o.cql, o.stmts = e.sql, e.cql, e.stmts
+		o.registry, o.registryKey = r, key
+		return nil
+	}
 
-	// Check if the connection exists
-	if val, ok := dbc.m[dbSource]; ok {
-		*o = *val
-		return val.err
+	o.Debugf("connection %s: open", key)
+	e.err = open(o)
+	if e.err != nil {
+		// Evict the entry so the next DB can try again, the DBs waiting on it get the error
+		r.mu.Lock()
+		if r.m[key] == e {
+			delete(r.m, key)
+		}
+		r.mu.Unlock()
+		close(e.ready)
+		return e.err
 	}
+	e.sql, e.cql, e.stmts = o.sql, o.cql, o.stmts
+	o.registry, o.registryKey = r, key
+	close(e.ready)
+	return nil
+}
 
-	var db *sqlx.DB
-	// Try to open a connection if it doesn't exist
-	db, o.err = sqlx.Open(o.DBSource.String(), dbSource)
-
-	if o.err == nil {
-		// Convert sql to sqlx
-		o.sql = db
-
-		o.sql.Mapper = reflectx.NewMapperTagFunc(
-			"json",
-			preMapFunc(o.mapFunc),
-			preMapFunc(o.tagMapFunc),
-		)
+// release drops the reference of the DB, the last reference closes the connection
+func (r *Registry) release(o *DB) error {
+	r.mu.Lock()
+	e, ok := r.m[o.registryKey]
+	if !ok || o.released {
+		r.mu.Unlock()
+		return nil
 	}
+	o.released = true
+	e.refs--
+	if e.refs > 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	delete(r.m, o.registryKey)
+	r.mu.Unlock()
 
-	// Add it to the pool so that some other service can reference it
-	dbc.m[dbSource] = o
+	return e.close()
+}
 
-	// Run migrations
-	if o.MigratePath != "" && o.Migrate {
-		if err := RunMigrations(o); err != nil {
-			return err
-		}
+func (e *registryEntry) close() error {
+	if e.stmts != nil {
+		e.stmts.close()
 	}
-	return err
+	if e.cql != nil {
+		e.cql.Close()
+	}
+	if e.sql != nil {
+		return e.sql.Close()
+	}
+	return nil
 }
 
-// CQL connection currently does not support query string arguments
-func (dbc *dbConnections) GetCQLConnection(o *DB) error {
-	dbc.Lock()
-	defer dbc.Unlock()
-	// Check if the connection exists
+// openSQL opens the connection pool of the DB and runs the migrations
+func openSQL(o *DB) error {
 	dbSource, err := o.getDataSource()
 	if err != nil {
 		return err
 	}
 
-	if val, ok := dbc.m[dbSource]; ok {
-		*o = *val
-		return val.err
+	var db *sqlx.DB
+	switch {
+	case o.TLS.Enabled() && o.DBSource == DBSource_postgres:
+		db, err = o.openPostgresTLS(dbSource)
+	case o.TLS.Enabled() && o.DBSource == DBSource_mysql:
+		if err := o.registerMySQLTLS(); err != nil {
+			return err
+		}
+		db, err = sqlx.Open(o.DBSource.String(), dbSource)
+	default:
+		db, err = sqlx.Open(o.DBSource.String(), dbSource)
+	}
+	if err != nil {
+		return err
+	}
+	o.Pool.forSource(o.DBSource).applySQL(db.DB)
+	// Convert sql to sqlx
+	o.sql = db
+	o.sql.Mapper = reflectx.NewMapperTagFunc(
+		"json",
+		table.PreMapFunc(o.mapFunc),
+		table.PreMapFunc(o.tagMapFunc),
+	)
+	if o.StmtCacheSize > 0 {
+		o.stmts = newStmtCache(o.StmtCacheSize)
 	}
 
+	// Run migrations
+	if o.MigratePath != "" && o.Migrate {
+		if err := RunMigrations(o); err != nil {
+			o.closeConnection()
+			return err
+		}
+	}
+	return nil
+}
+
+// openCQL opens the session of the DB and runs the migrations.
+// CQL connection currently does not support query string arguments
+func openCQL(o *DB) error {
+	var err error
 	cluster := gocql.NewCluster(o.Hosts...)
 
 	if o.Timeout != 0 {
@@ -132,7 +298,12 @@ func (dbc *dbConnections) GetCQLConnection(o *DB) error {
 	cluster.ProtoVersion = 3
 
 	// SSL
-	if o.CaPath != "" {
+	if o.TLS.Enabled() {
+		cluster.SslOpts, err = o.cqlSslOptions()
+		if err != nil {
+			return err
+		}
+	} else if o.CaPath != "" {
 		cluster.SslOpts = &gocql.SslOptions{
 			CaPath: o.CaPath,
 		}
@@ -141,6 +312,9 @@ func (dbc *dbConnections) GetCQLConnection(o *DB) error {
 	// Consistency
 	cluster.Consistency = o.Consistency
 
+	o.CQLPolicy.applyCQL(cluster)
+	o.Pool.applyCQL(cluster)
+
 	// Create keyspace on migration, it should fail if we try to connect to an unmigrated db
 	if o.Migrate && o.AppEnv == development {
 		o.Debugf("creating keyspace name")
@@ -148,8 +322,9 @@ func (dbc *dbConnections) GetCQLConnection(o *DB) error {
 		if err != nil {
 			return fmt.Errorf("create session: %v", err)
 		}
-
-		if err := ts.Query(CreateListingsDevKeyspaceStmt(o.DBName)).Exec(); err != nil {
+		err = ts.Query(CreateListingsDevKeyspaceStmt(o.DBName)).Exec()
+		ts.Close()
+		if err != nil {
 			return err
 		}
 	}
@@ -163,109 +338,31 @@ func (dbc *dbConnections) GetCQLConnection(o *DB) error {
 
 	// Wrap session on creation, gocqlx session embeds gocql.Session pointer.
 	session := gocqlx.NewSession(ts)
-	session.Mapper = cqlreflectx.NewMapperTagFunc("json", preMapFunc(o.mapFunc), preMapFunc(o.tagMapFunc))
+	session.Mapper = cqlreflectx.NewMapperTagFunc("json", table.PreMapFunc(o.mapFunc), table.PreMapFunc(o.tagMapFunc))
 	o.cql = &session
 
-	// Add it to the pool so that some other service can reference it
-	dbc.m[dbSource] = o
-
 	// Run migrations
 	if o.MigratePath != "" && o.Migrate {
 		o.Debugf("running migrations")
 		if err := RunMigrations(o); err != nil {
+			o.closeConnection()
 			return err
 		}
 	}
-
-	return err
+	return nil
 }
 
 func CreateListingsDevKeyspaceStmt(keyspace string) string {
 	return `CREATE KEYSPACE IF NOT EXISTS ` + keyspace + ` WITH replication = {'class': 'SimpleStrategy', 'replication_factor' : '1'}`
 }
 
+// RunMigrations applies every pending migration of the DB
 func RunMigrations(o *DB) error {
-	var driver database.Driver
-	var err error
-
-	switch o.DBSource {
-	case DBSource_sqlite:
-		driver, err = sqlite.WithInstance(o.sql.DB, &sqlite.Config{
-			DatabaseName: o.DBName,
-		})
-		if err != nil {
-			return fmt.Errorf("sqlite instance: %w", err)
-		}
-	case DBSource_postgres:
-		driver, err = postgres.WithInstance(o.sql.DB, &postgres.Config{
-			DatabaseName: o.DBName,
-		})
-		if err != nil {
-			return fmt.Errorf("postgres instance: %w", err)
-		}
-	case DBSource_mysql:
-		driver, err = mysql.WithInstance(o.sql.DB, &mysql.Config{
-			DatabaseName: o.DBName,
-		})
-		if err != nil {
-			return fmt.Errorf("mysql instance: %w", err)
-		}
-	case DBSource_cql:
-		driver, err = cassandra.WithInstance(o.cql.Session, &cassandra.Config{
-			// CQL connection currently does not support query string arguments
-			// Manually override the multi statments flag
-			MultiStatementEnabled: true,
-			KeyspaceName:          o.DBName,
-		})
-		if err != nil {
-			return fmt.Errorf("cql instance: %w", err)
-		}
-	default:
-		return errors.New("db driver not supported")
-	}
-
-	var m *migrate.Migrate
-
-	if o.MigrateFS != nil {
-
-		source, err := iofs.New(o.MigrateFS, o.MigratePath)
-		if err != nil {
-			return fmt.Errorf("new fs: %w", err)
-		}
-
-		m, err = migrate.NewWithInstance("iofs", source, o.DBName, driver)
-		if err != nil {
-			return fmt.Errorf("fs instance: %w", err)
-		}
-
-	} else {
-
-		m, err = migrate.NewWithDatabaseInstance(
-			o.GetMigratePath(),
-			o.DBName,
-			driver,
-		)
-		if err != nil {
-			return fmt.Errorf("migrations instance: %v", err)
-		}
-
-	}
-
-	if err := m.Up(); err != nil {
-		if !errors.Is(err, migrate.ErrNoChange) {
-			return fmt.Errorf("migrations up: %v", err)
-		}
-		o.Debugf("migrate up: %v", err)
+	m, err := o.Migrator()
+	if err != nil {
+		return err
 	}
-	o.Debugf("migrate up: success")
-	return nil
-}
-
-func preMapFunc(f func(string) string) func(string) string {
-	return func(s string) string {
-		ss := strings.Split(s, ",")
-		out := f(ss[0])
+	defer m.Close()
 
-		return out
-	}
+	return m.Up()
 }
@@ -1,45 +1,129 @@
 package sql
 
 import (
-	"reflect"
+	"errors"
+	"strings"
 	"testing"
-
-	cqlreflectx "github.com/scylladb/go-reflectx"
+	"testing/fstest"
 )
 
-func Test_preMapFunc(t *testing.T) {
-	type args struct {
-		f   func(string) string
-		tag string
+func TestRegistry_fingerprint(t *testing.T) {
+	r := NewRegistry()
+	base := DB{DBSource: DBSource_postgres, User: "postgres", Password: "secret", Hosts: []string{"127.0.0.1"}, Port: "5432", DBName: "test_db"}
+	key, err := r.fingerprint(&base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(key, "secret") || !strings.HasPrefix(key, "postgres:") {
+		t.Errorf("Registry.fingerprint() = %q, want a postgres key without the password", key)
 	}
+
 	tests := []struct {
-		name string
-		args args
-		want string
+		name     string
+		change   func(o *DB)
+		wantSame bool
 	}{
-
-		{
-			name: "should pass camel to snake; value: userId,omitempty",
-			args: args{
-				f:   cqlreflectx.CamelToSnakeASCII,
-				tag: "userId,comitempty",
-			},
-			want: "user_id",
-		},
-		{
-			name: "should pass camel to snake; value: userId",
-			args: args{
-				f:   cqlreflectx.CamelToSnakeASCII,
-				tag: "userId",
-			},
-			want: "user_id",
-		},
+		{name: "should pass; same config", change: func(o *DB) {}, wantSame: true},
+		{name: "should pass; migrate", change: func(o *DB) { o.Migrate = true }},
+		{name: "should pass; migrate path", change: func(o *DB) { o.MigratePath = "database/psql" }},
+		{name: "should pass; migrate fs", change: func(o *DB) { o.MigrateFS = fstest.MapFS{} }},
+		{name: "should pass; migrate dry run", change: func(o *DB) { o.MigrateDryRun = true }},
+		{name: "should pass; password", change: func(o *DB) { o.Password = "other" }},
+		{name: "should pass; db name", change: func(o *DB) { o.DBName = "other" }},
+		{name: "should pass; hosts", change: func(o *DB) { o.Hosts = []string{"127.0.0.2"} }},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := preMapFunc(tt.args.f); !reflect.DeepEqual(got(tt.args.tag), tt.want) {
-				t.Errorf("preMapFunc() = %v, want %v", got(tt.args.tag), tt.want)
+			o := base
+			tt.change(&o)
+			got, err := r.fingerprint(&o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got == key) != tt.wantSame {
+				t.Errorf("Registry.fingerprint() = %q, base %q, wantSame %v", got, key, tt.wantSame)
 			}
 		})
 	}
 }
+
+func TestRegistry_acquire(t *testing.T) {
+	r := NewRegistry()
+	errOpen := errors.New("open failed")
+	opens := 0
+	open := func(err error) func(*DB) error {
+		return func(*DB) error {
+			opens++
+			return err
+		}
+	}
+	newDB := func() *DB { return &DB{DBSource: DBSource_sqlite, DBName: "test.db"} }
+
+	if err := r.acquire(newDB(), open(errOpen)); !errors.Is(err, errOpen) {
+		t.Fatalf("Registry.acquire() error = %v, want %v", err, errOpen)
+	}
+	if r.Len() != 0 {
+		t.Errorf("Registry.Len() = %d after a failed open, want 0", r.Len())
+	}
+
+	a, b := newDB(), newDB()
+	for _, o := range []*DB{a, b} {
+		if err := r.acquire(o, open(nil)); err != nil {
+			t.Fatalf("Registry.acquire() error = %v", err)
+		}
+	}
+	if opens != 2 || r.Len() != 1 {
+		t.Errorf("opens = %d, Registry.Len() = %d, want 2 and 1", opens, r.Len())
+	}
+
+	// Closing a twice must not drop the reference of b
+	a.Close()
+	a.Close()
+	if r.Len() != 1 {
+		t.Errorf("Registry.Len() = %d after the first close, want 1", r.Len())
+	}
+	b.Close()
+	if r.Len() != 0 {
+		t.Errorf("Registry.Len() = %d after the last close, want 0", r.Len())
+	}
+}
+
+func TestDB_connect(t *testing.T) {
+	r := NewRegistry()
+	opens := 0
+	open := func(*DB) error {
+		opens++
+		return nil
+	}
+	// closures of the same func with other captured state can't be told apart
+	prefix := func(p string) func(string) string {
+		return func(s string) string { return p + s }
+	}
+
+	var dbs []*DB
+	for _, p := range []string{"a_", "b_"} {
+		o, err := NewConfig(WithDBSource("sqlite"), WithDBName("test.db"), WithRegistry(r), WithMapFunc(prefix(p)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := o.connect(open); err != nil {
+			t.Fatalf("DB.connect() error = %v", err)
+		}
+		dbs = append(dbs, o)
+	}
+	if opens != 2 || r.Len() != 0 {
+		t.Errorf("opens = %d, Registry.Len() = %d, want 2 and 0", opens, r.Len())
+	}
+
+	shared := &DB{DBSource: DBSource_sqlite, DBName: "test.db", registry: r}
+	if err := shared.connect(open); err != nil {
+		t.Fatalf("DB.connect() error = %v", err)
+	}
+	if opens != 3 || r.Len() != 1 {
+		t.Errorf("opens = %d, Registry.Len() = %d, want 3 and 1", opens, r.Len())
+	}
+	shared.Close()
+	if r.Len() != 0 {
+		t.Errorf("Registry.Len() = %d after close, want 0", r.Len())
+	}
+}
@@ -0,0 +1,219 @@
+//go:build sqlite
+// +build sqlite
+
+package sql
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type contextTestUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// newTestSQLite opens a sqlite file of the test with a users table that has one user
+func newTestSQLite(t *testing.T) *DB {
+	t.Helper()
+	db, err := New(
+		WithDBSource("sqlite"),
+		WithDBName(filepath.Join(t.TempDir(), "test.db")),
+		WithRegistry(NewRegistry()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.ExecStmt("CREATE TABLE users (id int PRIMARY KEY, name text)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO users (id, name) VALUES (?, ?)", []string{"id", "name"}, contextTestUser{ID: 1, Name: "joe"}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestDB_contextCanceled_sqlite(t *testing.T) {
+	db := newTestSQLite(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{name: "should fail; SelectContext", run: func() error {
+			var users []contextTestUser
+			return db.SelectContext(ctx, &users, "SELECT id, name FROM users WHERE id = ?", []string{"id"}, contextTestUser{ID: 1})
+		}},
+		{name: "should fail; GetContext", run: func() error {
+			var user contextTestUser
+			return db.GetContext(ctx, &user, "SELECT id, name FROM users WHERE id = ?", []string{"id"}, contextTestUser{ID: 1})
+		}},
+		{name: "should fail; PingContext", run: func() error {
+			return db.PingContext(ctx)
+		}},
+		{name: "should fail; ExecStmtContext", run: func() error {
+			return db.ExecStmtContext(ctx, "DELETE FROM users")
+		}},
+		{name: "should fail; ExecContext", run: func() error {
+			return db.ExecContext(ctx, "INSERT INTO users (id, name) VALUES (?, ?)", []string{"id", "name"}, contextTestUser{ID: 2})
+		}},
+		{name: "should fail; ExecMapContext", run: func() error {
+			return db.ExecMapContext(ctx, "INSERT INTO users (id, name) VALUES (?, ?)", []string{"id", "name"}, map[string]interface{}{"id": 2, "name": "jane"})
+		}},
+		{name: "should fail; ExecManyContext", run: func() error {
+			return db.ExecManyContext(ctx, "INSERT INTO users (id, name) VALUES (?, ?)", []string{"id", "name"}, contextTestUser{ID: 2})
+		}},
+		{name: "should fail; QueryRows", run: func() error {
+			rows, err := db.QueryRows(ctx, "SELECT id, name FROM users")
+			if err == nil {
+				rows.Close()
+			}
+			return err
+		}},
+		{name: "should fail; QueryxRows", run: func() error {
+			rows, err := db.QueryxRows(ctx, "SELECT id, name FROM users WHERE id = ?", []string{"id"}, 1)
+			if err == nil {
+				rows.Close()
+			}
+			return err
+		}},
+		{name: "should fail; QueryRowContext", run: func() error {
+			var id int
+			return db.QueryRowContext(ctx, "SELECT id FROM users").Scan(&id)
+		}},
+		{name: "should fail; WriteBatchContext", run: func() error {
+			return db.WriteBatchContext(ctx, []string{"INSERT INTO users (id, name) VALUES (?, ?)"}, [][]string{{"id", "name"}}, []interface{}{contextTestUser{ID: 2}})
+		}},
+		{name: "should fail; RunInTx", run: func() error {
+			return db.RunInTx(ctx, func(tx *Tx) error {
+				return tx.ExecContext(ctx, "INSERT INTO users (id, name) VALUES (?, ?)", []string{"id", "name"}, contextTestUser{ID: 2})
+			})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); !errors.Is(err, context.Canceled) {
+				t.Errorf("error = %v, want %v", err, context.Canceled)
+			}
+		})
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM users").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("count = %d, want 1, a canceled statement changed the table", count)
+	}
+}
+
+func TestDB_Exec_namedParameters_sqlite(t *testing.T) {
+	db := newTestSQLite(t)
+
+	if err := db.Exec("INSERT INTO users (id, name) VALUES (:id, :name)", nil, map[string]interface{}{"id": 2, "name": "jane"}); err != nil {
+		t.Fatalf("DB.Exec() error = %v", err)
+	}
+	var user contextTestUser
+	if err := db.Get(&user, "SELECT id, name FROM users WHERE id = :id", nil, map[string]interface{}{"id": 2}); err != nil {
+		t.Fatalf("DB.Get() error = %v", err)
+	}
+	if want := (contextTestUser{ID: 2, Name: "jane"}); user != want {
+		t.Errorf("DB.Get() = %v, want %v", user, want)
+	}
+}
+
+func TestDB_RunInTx_sqlite(t *testing.T) {
+	db, err := New(
+		WithDBSource("sqlite"),
+		WithDBName(filepath.Join(t.TempDir(), "test.db")),
+		WithRegistry(NewRegistry()),
+		WithPool(PoolConfig{MaxOpenConns: 1, MaxIdleConns: 1}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.ExecStmt("CREATE TABLE users (id int PRIMARY KEY, name text)"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the tx holds the only connection of the pool, the reads must not wait for another one
+	var users []contextTestUser
+	var user contextTestUser
+	if err := db.RunInTx(ctx, func(tx *Tx) error {
+		if err := tx.ExecContext(ctx, "INSERT INTO users (id, name) VALUES (?, ?)", []string{"id", "name"}, contextTestUser{ID: 1, Name: "joe"}); err != nil {
+			return err
+		}
+		if err := tx.SelectContext(ctx, &users, "SELECT id, name FROM users WHERE id = ?", []string{"id"}, contextTestUser{ID: 1}); err != nil {
+			return err
+		}
+		return tx.GetContext(ctx, &user, "SELECT id, name FROM users WHERE id = ?", []string{"id"}, contextTestUser{ID: 1})
+	}); err != nil {
+		t.Fatalf("DB.RunInTx() error = %v", err)
+	}
+	want := contextTestUser{ID: 1, Name: "joe"}
+	if len(users) != 1 || users[0] != want || user != want {
+		t.Errorf("DB.RunInTx() read %v and %v, want %v", users, user, want)
+	}
+
+	// database/sql already rolled back, ie. after ctx was canceled
+	errFn := errors.New("fn")
+	err = db.RunInTx(ctx, func(tx *Tx) error {
+		if err := tx.sql.Rollback(); err != nil {
+			return err
+		}
+		return errFn
+	})
+	if err != errFn {
+		t.Errorf("DB.RunInTx() error = %v, want %v", err, errFn)
+	}
+}
+
+func TestDB_WriteBatchContext_sqlite(t *testing.T) {
+	db := newTestSQLite(t)
+
+	queries := []string{
+		"INSERT INTO users (id, name) VALUES (?, ?)",
+		"INSERT INTO users (id, name) VALUES (?, ?)",
+	}
+	names := [][]string{{"id", "name"}, {"id", "name"}}
+	if err := db.WriteBatchContext(context.Background(), queries, names, []interface{}{
+		contextTestUser{ID: 2, Name: "jane"},
+		contextTestUser{ID: 3, Name: "jim"},
+	}); err != nil {
+		t.Fatalf("DB.WriteBatchContext() error = %v", err)
+	}
+
+	// the duplicate key rolls back the whole batch
+	if err := db.WriteBatchContext(context.Background(), queries, names, []interface{}{
+		contextTestUser{ID: 4, Name: "jack"},
+		contextTestUser{ID: 1, Name: "joe"},
+	}); err == nil {
+		t.Fatal("DB.WriteBatchContext() error = nil, want the duplicate key")
+	}
+
+	var users []contextTestUser
+	if err := db.Select(&users, "SELECT id, name FROM users ORDER BY id", nil, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	want := []contextTestUser{{ID: 1, Name: "joe"}, {ID: 2, Name: "jane"}, {ID: 3, Name: "jim"}}
+	if len(users) != len(want) {
+		t.Fatalf("DB.WriteBatchContext() wrote %v, want %v", users, want)
+	}
+	for i := range want {
+		if users[i] != want[i] {
+			t.Errorf("DB.WriteBatchContext() wrote %v, want %v", users, want)
+			break
+		}
+	}
+}
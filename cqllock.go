@@ -0,0 +1,265 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/cassandra"
+	"github.com/golang-migrate/migrate/v4/database/multistmt"
+)
+
+const (
+	// CQLMigrationsLockTable holds the lease of the instance that is running the cql migrations
+	CQLMigrationsLockTable = "schema_migrations_lock"
+
+	defaultMigrateLockTTL     = 30 * time.Second
+	defaultMigrateLockTimeout = 5 * time.Minute
+	cqlLockName               = "migrate"
+)
+
+var (
+	ErrMigrateLockTimeout = errors.New("timeout waiting for the migrations lock")
+	ErrMigrateLockLost    = errors.New("migrations lock was lost")
+)
+
+// leaseStore keeps a lease row that expires after its ttl
+type leaseStore interface {
+	// acquire creates the lease if there is none and returns the current holder when there is one
+	acquire(ctx context.Context, owner string, ttl time.Duration) (bool, string, error)
+	// renew extends the lease if owner still holds it
+	renew(ctx context.Context, owner string, ttl time.Duration) (bool, error)
+	release(ctx context.Context, owner string) error
+}
+
+// lease is held while the migrations run and renewed in the background so that it only expires
+// when its holder died
+type lease struct {
+	store   leaseStore
+	owner   string
+	ttl     time.Duration
+	timeout time.Duration
+	debugf  func(format string, args ...interface{})
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+func newLease(store leaseStore, ttl, timeout time.Duration, debugf func(string, ...interface{})) (*lease, error) {
+	owner, err := newLeaseOwner()
+	if err != nil {
+		return nil, err
+	}
+	return &lease{store: store, owner: owner, ttl: ttl, timeout: timeout, debugf: debugf}, nil
+}
+
+// newLeaseOwner identifies this process in the lock row
+func newLeaseOwner() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("lease owner: %w", err)
+	}
+	host, _ := os.Hostname()
+	return host + "-" + hex.EncodeToString(b), nil
+}
+
+// lock waits until the lease is acquired, retrying a few times per ttl
+func (l *lease) lock(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	retry := time.NewTicker(l.ttl / 4)
+	defer retry.Stop()
+	for {
+		acquired, holder, err := l.store.acquire(ctx, l.owner, l.ttl)
+		if err != nil {
+			return fmt.Errorf("acquire lock: %w", err)
+		}
+		if acquired {
+			break
+		}
+		l.debugf("migrations lock is held by %s", holder)
+
+		select {
+		case <-ctx.Done():
+			return ErrMigrateLockTimeout
+		case <-retry.C:
+		}
+	}
+	l.debugf("migrations lock acquired by %s", l.owner)
+
+	renewCtx, renewCancel := context.WithCancel(context.Background())
+	l.mu.Lock()
+	l.cancel, l.done, l.err = renewCancel, make(chan struct{}), nil
+	l.mu.Unlock()
+	go l.renew(renewCtx, l.done)
+	return nil
+}
+
+func (l *lease) renew(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		held, err := l.store.renew(ctx, l.owner, l.ttl)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			// The lease is still valid until its ttl, the next tick retries
+			l.debugf("renew migrations lock: %v", err)
+			continue
+		}
+		if !held {
+			l.mu.Lock()
+			l.err = ErrMigrateLockLost
+			l.mu.Unlock()
+			return
+		}
+	}
+}
+
+// unlock stops the renewal and deletes the lease, ErrMigrateLockLost is returned when
+// another instance could have taken the lease while it was held
+func (l *lease) unlock(ctx context.Context) error {
+	l.mu.Lock()
+	cancel, done := l.cancel, l.done
+	l.cancel, l.done = nil, nil
+	l.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	<-done
+
+	if err := l.store.release(ctx, l.owner); err != nil {
+		return fmt.Errorf("release lock: %w", err)
+	}
+	l.debugf("migrations lock released by %s", l.owner)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// cqlLeaseStore keeps the lease in a row of CQLMigrationsLockTable with lightweight transactions
+type cqlLeaseStore struct {
+	session *gocql.Session
+}
+
+func (s cqlLeaseStore) createTable(ctx context.Context) error {
+	stmt := `CREATE TABLE IF NOT EXISTS ` + CQLMigrationsLockTable + ` (name text PRIMARY KEY, owner text)`
+	if err := s.session.Query(stmt).WithContext(ctx).Exec(); err != nil {
+		return err
+	}
+	return s.session.AwaitSchemaAgreement(ctx)
+}
+
+func (s cqlLeaseStore) acquire(ctx context.Context, owner string, ttl time.Duration) (bool, string, error) {
+	existing := map[string]interface{}{}
+	applied, err := s.session.Query(
+		`INSERT INTO `+CQLMigrationsLockTable+` (name, owner) VALUES (?, ?) IF NOT EXISTS USING TTL ?`,
+		cqlLockName, owner, ttlSeconds(ttl),
+	).WithContext(ctx).MapScanCAS(existing)
+	if err != nil {
+		return false, "", err
+	}
+	holder, _ := existing["owner"].(string)
+	return applied, holder, nil
+}
+
+func (s cqlLeaseStore) renew(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	return s.session.Query(
+		`UPDATE `+CQLMigrationsLockTable+` USING TTL ? SET owner = ? WHERE name = ? IF owner = ?`,
+		ttlSeconds(ttl), owner, cqlLockName, owner,
+	).WithContext(ctx).MapScanCAS(map[string]interface{}{})
+}
+
+func (s cqlLeaseStore) release(ctx context.Context, owner string) error {
+	_, err := s.session.Query(
+		`DELETE FROM `+CQLMigrationsLockTable+` WHERE name = ? IF owner = ?`,
+		cqlLockName, owner,
+	).WithContext(ctx).MapScanCAS(map[string]interface{}{})
+	return err
+}
+
+func ttlSeconds(ttl time.Duration) int {
+	if s := int(ttl / time.Second); s > 0 {
+		return s
+	}
+	return 1
+}
+
+// cqlLockDriver replaces the in process lock of the golang-migrate cassandra driver with a lease
+// that is shared by every instance, and waits for the schema to agree after every statement
+type cqlLockDriver struct {
+	sharedDriver
+	store   cqlLeaseStore
+	lease   *lease
+	created bool
+}
+
+func (o *DB) newCQLLockDriver(driver database.Driver) (database.Driver, error) {
+	store := cqlLeaseStore{session: o.cql.Session}
+	l, err := newLease(store, o.MigrateLockTTL, o.MigrateLockTimeout, o.Debugf)
+	if err != nil {
+		return nil, err
+	}
+	return &cqlLockDriver{sharedDriver: sharedDriver{driver}, store: store, lease: l}, nil
+}
+
+// Lock creates the lock table the first time it is needed, so a migrator that only reads
+// the version or the plan does not change the schema
+func (d *cqlLockDriver) Lock() error {
+	ctx := context.Background()
+	if !d.created {
+		if err := d.store.createTable(ctx); err != nil {
+			return fmt.Errorf("create lock table: %w", err)
+		}
+		d.created = true
+	}
+	return d.lease.lock(ctx)
+}
+
+func (d *cqlLockDriver) Unlock() error {
+	return d.lease.unlock(context.Background())
+}
+
+// Run runs the statements of the migration one at a time, a statement can use the tables
+// created by the statement before it once the schema agrees
+func (d *cqlLockDriver) Run(migration io.Reader) error {
+	var err error
+	if e := multistmt.Parse(migration, []byte(";"), cassandra.DefaultMultiStatementMaxSize, func(stmt []byte) bool {
+		if len(bytes.TrimSpace(stmt)) == 0 {
+			return true
+		}
+		if err = d.Driver.Run(bytes.NewReader(stmt)); err != nil {
+			return false
+		}
+		if err = d.store.session.AwaitSchemaAgreement(context.Background()); err != nil {
+			err = fmt.Errorf("schema agreement: %w", err)
+			return false
+		}
+		return true
+	}); e != nil {
+		return e
+	}
+	return err
+}
@@ -0,0 +1,131 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memLeaseStore is a leaseStore that expires the lease like the ttl of a cql row
+type memLeaseStore struct {
+	mu      sync.Mutex
+	owner   string
+	expires time.Time
+	renews  int
+}
+
+func (s *memLeaseStore) acquire(ctx context.Context, owner string, ttl time.Duration) (bool, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owner != "" && time.Now().Before(s.expires) {
+		return false, s.owner, nil
+	}
+	s.owner, s.expires = owner, time.Now().Add(ttl)
+	return true, "", nil
+}
+
+func (s *memLeaseStore) renew(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owner != owner {
+		return false, nil
+	}
+	s.renews++
+	s.expires = time.Now().Add(ttl)
+	return true, nil
+}
+
+func (s *memLeaseStore) release(ctx context.Context, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owner == owner {
+		s.owner = ""
+	}
+	return nil
+}
+
+func (s *memLeaseStore) steal(owner string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.owner = owner
+}
+
+func newTestLease(t *testing.T, store leaseStore, ttl, timeout time.Duration) *lease {
+	t.Helper()
+	l, err := newLease(store, ttl, timeout, t.Logf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func Test_lease(t *testing.T) {
+	ctx := context.Background()
+	ttl := 40 * time.Millisecond
+
+	t.Run("should pass; second instance waits for the first to unlock", func(t *testing.T) {
+		store := &memLeaseStore{}
+		first := newTestLease(t, store, ttl, time.Second)
+		second := newTestLease(t, store, ttl, time.Second)
+
+		if err := first.lock(ctx); err != nil {
+			t.Fatalf("first lock() error = %v", err)
+		}
+
+		locked := make(chan error)
+		go func() { locked <- second.lock(ctx) }()
+
+		// Held for longer than the ttl, the renewal keeps the second instance out
+		time.Sleep(3 * ttl)
+		select {
+		case err := <-locked:
+			t.Fatalf("second lock() returned while the first held it, error = %v", err)
+		default:
+		}
+
+		if err := first.unlock(ctx); err != nil {
+			t.Fatalf("first unlock() error = %v", err)
+		}
+		if err := <-locked; err != nil {
+			t.Fatalf("second lock() error = %v", err)
+		}
+		if err := second.unlock(ctx); err != nil {
+			t.Fatalf("second unlock() error = %v", err)
+		}
+		if store.renews == 0 {
+			t.Error("lease was never renewed")
+		}
+	})
+
+	t.Run("should fail; timeout", func(t *testing.T) {
+		store := &memLeaseStore{}
+		first := newTestLease(t, store, ttl, time.Second)
+		second := newTestLease(t, store, ttl, 2*ttl)
+
+		if err := first.lock(ctx); err != nil {
+			t.Fatalf("first lock() error = %v", err)
+		}
+		defer first.unlock(ctx)
+
+		if err := second.lock(ctx); !errors.Is(err, ErrMigrateLockTimeout) {
+			t.Errorf("second lock() error = %v, want %v", err, ErrMigrateLockTimeout)
+		}
+	})
+
+	t.Run("should fail; lost lease", func(t *testing.T) {
+		store := &memLeaseStore{}
+		l := newTestLease(t, store, ttl, time.Second)
+
+		if err := l.lock(ctx); err != nil {
+			t.Fatalf("lock() error = %v", err)
+		}
+		store.steal("other")
+		time.Sleep(2 * ttl)
+
+		if err := l.unlock(ctx); !errors.Is(err, ErrMigrateLockLost) {
+			t.Errorf("unlock() error = %v, want %v", err, ErrMigrateLockLost)
+		}
+	})
+}
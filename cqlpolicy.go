@@ -0,0 +1,336 @@
+package sql
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// CQLPolicyConfig picks the cql hosts of the queries and how the failed queries and connections are retried.
+// The zero value of a field keeps the default of gocql.
+type CQLPolicyConfig struct {
+	HostSelection        CQLHostSelection        `json:"hostSelection"`
+	Retry                CQLRetryPolicy          `json:"retry"`
+	SpeculativeExecution CQLSpeculativeExecution `json:"speculativeExecution"`
+	Reconnection         CQLReconnectionPolicy   `json:"reconnection"`
+}
+
+// merge sets the policies of in that are not zero
+func (c *CQLPolicyConfig) merge(in CQLPolicyConfig) {
+	if in.HostSelection != (CQLHostSelection{}) {
+		c.HostSelection = in.HostSelection
+	}
+	if !in.Retry.isZero() {
+		c.Retry = in.Retry
+	}
+	if in.SpeculativeExecution != (CQLSpeculativeExecution{}) {
+		c.SpeculativeExecution = in.SpeculativeExecution
+	}
+	if in.Reconnection != (CQLReconnectionPolicy{}) {
+		c.Reconnection = in.Reconnection
+	}
+}
+
+// applyCQL sets the policies on the cluster, a new host selection policy is built for each cluster
+// as gocql can't share one between sessions
+func (c CQLPolicyConfig) applyCQL(cluster *gocql.ClusterConfig) {
+	if policy := c.HostSelection.policy(); policy != nil {
+		cluster.PoolConfig.HostSelectionPolicy = policy
+	}
+	if policy := c.Retry.policy(); policy != nil {
+		cluster.RetryPolicy = policy
+	}
+	if policy := c.Reconnection.policy(); policy != nil {
+		cluster.ReconnectionPolicy = policy
+	}
+}
+
+// CQLHostSelection is the order the hosts are tried in for a query, see WithCQLHostSelection
+type CQLHostSelection struct {
+	// LocalDC is the datacenter that is tried first, the other datacenters are used when its hosts are down
+	LocalDC string `json:"localDC"`
+	// LocalRack is the rack of LocalDC that is tried before the other racks
+	LocalRack string `json:"localRack"`
+	// TokenAware tries the replicas of the partition of the query first
+	TokenAware bool `json:"tokenAware"`
+	// ShuffleReplicas spreads the queries of a partition over its replicas, it needs TokenAware
+	ShuffleReplicas bool `json:"shuffleReplicas"`
+	// NonLocalReplicasFallback tries the replicas in the other datacenters before the hosts of LocalDC
+	// that are not replicas, it needs TokenAware
+	NonLocalReplicasFallback bool `json:"nonLocalReplicasFallback"`
+}
+
+func (s CQLHostSelection) validate() error {
+	if s.LocalRack != "" && s.LocalDC == "" {
+		return errors.New("cql local rack needs a local dc")
+	}
+	if (s.ShuffleReplicas || s.NonLocalReplicasFallback) && !s.TokenAware {
+		return errors.New("cql shuffle replicas and non local replicas fallback need token aware")
+	}
+	return nil
+}
+
+// policy returns nil when the selection is zero so the default of gocql is kept
+func (s CQLHostSelection) policy() gocql.HostSelectionPolicy {
+	if s == (CQLHostSelection{}) {
+		return nil
+	}
+
+	var policy gocql.HostSelectionPolicy
+	switch {
+	case s.LocalRack != "":
+		policy = newRackAwarePolicy(s.LocalDC, s.LocalRack)
+	case s.LocalDC != "":
+		policy = gocql.DCAwareRoundRobinPolicy(s.LocalDC)
+	default:
+		policy = gocql.RoundRobinHostPolicy()
+	}
+	if !s.TokenAware {
+		return policy
+	}
+
+	opts := append(optional(s.ShuffleReplicas, gocql.ShuffleReplicas()), optional(s.NonLocalReplicasFallback, gocql.NonLocalReplicasFallback())...)
+	return gocql.TokenAwareHostPolicy(policy, opts...)
+}
+
+// optional returns opt when it is set, the type of the token aware options is not exported
+func optional[T any](set bool, opt T) []T {
+	if set {
+		return []T{opt}
+	}
+	return nil
+}
+
+// CQLRetryPolicy retries the failed queries with an exponential backoff, see WithCQLRetryPolicy
+type CQLRetryPolicy struct {
+	// NumRetries is how many times a query is retried on the next host
+	NumRetries int `json:"numRetries"`
+	// MinBackoff and MaxBackoff bound the sleep between the retries, gocql defaults them to 100ms and 10s
+	MinBackoff time.Duration `json:"minBackoff"`
+	MaxBackoff time.Duration `json:"maxBackoff"`
+	// DowngradeConsistency are the consistencies of the retries in order, ie. LOCAL_QUORUM then LOCAL_ONE.
+	// A query is retried once per consistency and NumRetries is not used.
+	DowngradeConsistency []gocql.Consistency `json:"downgradeConsistency,omitempty"`
+}
+
+func (p CQLRetryPolicy) isZero() bool {
+	return p.NumRetries == 0 && p.MinBackoff == 0 && p.MaxBackoff == 0 && len(p.DowngradeConsistency) == 0
+}
+
+func (p CQLRetryPolicy) validate() error {
+	if p.NumRetries < 0 || p.MinBackoff < 0 || p.MaxBackoff < 0 {
+		return errors.New("cql retries and backoffs can't be negative")
+	}
+	if p.MaxBackoff != 0 && p.MinBackoff > p.MaxBackoff {
+		return errors.New("cql min backoff is more than the max backoff")
+	}
+	return nil
+}
+
+func (p CQLRetryPolicy) policy() gocql.RetryPolicy {
+	backoff := gocql.ExponentialBackoffRetryPolicy{NumRetries: p.NumRetries, Min: p.MinBackoff, Max: p.MaxBackoff}
+	if len(p.DowngradeConsistency) > 0 {
+		backoff.NumRetries = len(p.DowngradeConsistency)
+		return &downgradingRetryPolicy{
+			DowngradingConsistencyRetryPolicy: gocql.DowngradingConsistencyRetryPolicy{ConsistencyLevelsToTry: p.DowngradeConsistency},
+			backoff:                           backoff,
+		}
+	}
+	if p.NumRetries == 0 {
+		return nil
+	}
+	return &backoff
+}
+
+// downgradingRetryPolicy is gocql.DowngradingConsistencyRetryPolicy with the backoff of gocql.ExponentialBackoffRetryPolicy
+type downgradingRetryPolicy struct {
+	gocql.DowngradingConsistencyRetryPolicy
+	backoff gocql.ExponentialBackoffRetryPolicy
+}
+
+func (p *downgradingRetryPolicy) Attempt(q gocql.RetryableQuery) bool {
+	return p.DowngradingConsistencyRetryPolicy.Attempt(q) && p.backoff.Attempt(q)
+}
+
+// CQLSpeculativeExecution sends a read to the next host when the hosts before it have not answered after Delay,
+// the first answer is used. See WithCQLSpeculativeExecution.
+type CQLSpeculativeExecution struct {
+	// Attempts is how many more hosts a read is sent to
+	Attempts int           `json:"attempts"`
+	Delay    time.Duration `json:"delay"`
+}
+
+func (s CQLSpeculativeExecution) validate() error {
+	if s.Attempts < 0 {
+		return errors.New("cql speculative attempts can't be negative")
+	}
+	if s.Attempts > 0 && s.Delay <= 0 {
+		return errors.New("cql speculative delay must be positive")
+	}
+	return nil
+}
+
+func (s CQLSpeculativeExecution) policy() gocql.SpeculativeExecutionPolicy {
+	if s.Attempts == 0 {
+		return nil
+	}
+	return &gocql.SimpleSpeculativeExecution{NumAttempts: s.Attempts, TimeoutDelay: s.Delay}
+}
+
+// speculate marks a SELECT as idempotent so gocql runs the speculative execution of the config on it,
+// the other statements are never speculated as they may not be idempotent
+func (o *DB) speculate(q *gocql.Query) {
+	if !isSelect(q.Statement()) {
+		return
+	}
+	if policy := o.CQLPolicy.SpeculativeExecution.policy(); policy != nil {
+		q.Idempotent(true).SetSpeculativeExecutionPolicy(policy)
+	}
+}
+
+func isSelect(stmt string) bool {
+	stmt = strings.TrimSpace(stmt)
+	return len(stmt) >= len("SELECT") && strings.EqualFold(stmt[:len("SELECT")], "SELECT")
+}
+
+// CQLReconnectionPolicy is how a host is reconnected to before it is marked down, see WithCQLReconnectionPolicy
+type CQLReconnectionPolicy struct {
+	MaxRetries int `json:"maxRetries"`
+	// InitialInterval is the wait between the reconnections, or the first wait when MaxInterval is set
+	InitialInterval time.Duration `json:"initialInterval"`
+	// MaxInterval makes the waits grow exponentially from InitialInterval up to MaxInterval
+	MaxInterval time.Duration `json:"maxInterval"`
+}
+
+func (p CQLReconnectionPolicy) validate() error {
+	if p.MaxRetries < 0 || p.InitialInterval < 0 || p.MaxInterval < 0 {
+		return errors.New("cql reconnection retries and intervals can't be negative")
+	}
+	if p.MaxInterval != 0 && p.InitialInterval > p.MaxInterval {
+		return errors.New("cql reconnection initial interval is more than the max interval")
+	}
+	return nil
+}
+
+// policy keeps the 3 retries and 1s interval of gocql for the fields that are zero
+func (p CQLReconnectionPolicy) policy() gocql.ReconnectionPolicy {
+	if p == (CQLReconnectionPolicy{}) {
+		return nil
+	}
+	if p.MaxRetries == 0 {
+		p.MaxRetries = 3
+	}
+	if p.InitialInterval == 0 {
+		p.InitialInterval = time.Second
+	}
+	if p.MaxInterval != 0 {
+		return &gocql.ExponentialReconnectionPolicy{MaxRetries: p.MaxRetries, InitialInterval: p.InitialInterval, MaxInterval: p.MaxInterval}
+	}
+	return &gocql.ConstantReconnectionPolicy{MaxRetries: p.MaxRetries, Interval: p.InitialInterval}
+}
+
+// rackAwarePolicy is gocql.DCAwareRoundRobinPolicy with the hosts of the local rack first,
+// the hosts are tried round robin in the local rack, then the local datacenter, then the other datacenters
+type rackAwarePolicy struct {
+	localDC   string
+	localRack string
+
+	mu sync.Mutex
+	// tiers are replaced and not changed in place, Pick reads them without the lock
+	tiers    atomic.Value // [3][]*gocql.HostInfo
+	lastUsed uint64
+}
+
+func newRackAwarePolicy(localDC, localRack string) *rackAwarePolicy {
+	p := &rackAwarePolicy{localDC: localDC, localRack: localRack}
+	p.tiers.Store([3][]*gocql.HostInfo{})
+	return p
+}
+
+func (p *rackAwarePolicy) Init(*gocql.Session)                       {}
+func (p *rackAwarePolicy) KeyspaceChanged(gocql.KeyspaceUpdateEvent) {}
+func (p *rackAwarePolicy) SetPartitioner(string)                     {}
+
+// IsLocal is the datacenter, the token aware policy tries the replicas of the whole local datacenter first
+func (p *rackAwarePolicy) IsLocal(host *gocql.HostInfo) bool {
+	return host.DataCenter() == p.localDC
+}
+
+func (p *rackAwarePolicy) tier(dc, rack string) int {
+	switch {
+	case dc != p.localDC:
+		return 2
+	case rack != p.localRack:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (p *rackAwarePolicy) AddHost(host *gocql.HostInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	tiers := p.tiers.Load().([3][]*gocql.HostInfo)
+	i := p.tier(host.DataCenter(), host.Rack())
+	for _, h := range tiers[i] {
+		if h.ConnectAddress().Equal(host.ConnectAddress()) {
+			return
+		}
+	}
+	tiers[i] = append(tiers[i][:len(tiers[i]):len(tiers[i])], host)
+	p.tiers.Store(tiers)
+}
+
+func (p *rackAwarePolicy) RemoveHost(host *gocql.HostInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	tiers := p.tiers.Load().([3][]*gocql.HostInfo)
+	i := p.tier(host.DataCenter(), host.Rack())
+	hosts := make([]*gocql.HostInfo, 0, len(tiers[i]))
+	for _, h := range tiers[i] {
+		if !h.ConnectAddress().Equal(host.ConnectAddress()) {
+			hosts = append(hosts, h)
+		}
+	}
+	tiers[i] = hosts
+	p.tiers.Store(tiers)
+}
+
+func (p *rackAwarePolicy) HostUp(host *gocql.HostInfo)   { p.AddHost(host) }
+func (p *rackAwarePolicy) HostDown(host *gocql.HostInfo) { p.RemoveHost(host) }
+
+func (p *rackAwarePolicy) Pick(gocql.ExecutableQuery) gocql.NextHost {
+	shift := atomic.AddUint64(&p.lastUsed, 1)
+	tiers := p.tiers.Load().([3][]*gocql.HostInfo)
+	return roundRobin(int(shift%uint64(1<<31)), tiers[:]...)
+}
+
+// roundRobin returns the hosts that are up, all the hosts of a tier before the next tier,
+// starting at shift in each tier
+func roundRobin(shift int, tiers ...[]*gocql.HostInfo) gocql.NextHost {
+	tier, seen := 0, 0
+	return func() gocql.SelectedHost {
+		for ; tier < len(tiers); tier, seen = tier+1, 0 {
+			hosts := tiers[tier]
+			for seen < len(hosts) {
+				h := hosts[(shift+seen)%len(hosts)]
+				seen++
+				if h.IsUp() {
+					return selectedHost{h}
+				}
+			}
+		}
+		return nil
+	}
+}
+
+type selectedHost struct {
+	host *gocql.HostInfo
+}
+
+func (h selectedHost) Info() *gocql.HostInfo { return h.host }
+func (h selectedHost) Mark(error)            {}
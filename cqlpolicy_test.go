@@ -0,0 +1,199 @@
+package sql
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWithCQLPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []Option
+		want    CQLPolicyConfig
+		wantErr bool
+	}{
+		{
+			name: "should pass; defaults",
+		},
+		{
+			name: "should pass; every policy",
+			in: []Option{
+				WithCQLHostSelection(CQLHostSelection{LocalDC: "dc1", LocalRack: "rack1", TokenAware: true, ShuffleReplicas: true}),
+				WithCQLRetryPolicy(CQLRetryPolicy{NumRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Second}),
+				WithCQLSpeculativeExecution(2, 50*time.Millisecond),
+				WithCQLReconnectionPolicy(CQLReconnectionPolicy{MaxRetries: 5, InitialInterval: time.Second, MaxInterval: time.Minute}),
+			},
+			want: CQLPolicyConfig{
+				HostSelection:        CQLHostSelection{LocalDC: "dc1", LocalRack: "rack1", TokenAware: true, ShuffleReplicas: true},
+				Retry:                CQLRetryPolicy{NumRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Second},
+				SpeculativeExecution: CQLSpeculativeExecution{Attempts: 2, Delay: 50 * time.Millisecond},
+				Reconnection:         CQLReconnectionPolicy{MaxRetries: 5, InitialInterval: time.Second, MaxInterval: time.Minute},
+			},
+		},
+		{
+			name:    "should fail; local rack without local dc",
+			in:      []Option{WithCQLHostSelection(CQLHostSelection{LocalRack: "rack1"})},
+			wantErr: true,
+		},
+		{
+			name:    "should fail; shuffle replicas without token aware",
+			in:      []Option{WithCQLHostSelection(CQLHostSelection{LocalDC: "dc1", ShuffleReplicas: true})},
+			wantErr: true,
+		},
+		{
+			name:    "should fail; min backoff more than max backoff",
+			in:      []Option{WithCQLRetryPolicy(CQLRetryPolicy{NumRetries: 1, MinBackoff: time.Second, MaxBackoff: time.Millisecond})},
+			wantErr: true,
+		},
+		{
+			name:    "should fail; speculative execution without delay",
+			in:      []Option{WithCQLSpeculativeExecution(1, 0)},
+			wantErr: true,
+		},
+		{
+			name:    "should fail; negative reconnection retries",
+			in:      []Option{WithCQLReconnectionPolicy(CQLReconnectionPolicy{MaxRetries: -1})},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewConfig(tt.in...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got.CQLPolicy); diff != "" {
+				t.Errorf("NewConfig().CQLPolicy mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCQLPolicyConfig_applyCQL(t *testing.T) {
+	tests := []struct {
+		name          string
+		in            CQLPolicyConfig
+		wantHost      string
+		wantRetry     string
+		wantReconnect string
+	}{
+		{
+			name:          "should pass; defaults of gocql",
+			wantHost:      "<nil>",
+			wantRetry:     "<nil>",
+			wantReconnect: "*gocql.ConstantReconnectionPolicy",
+		},
+		{
+			name: "should pass; token aware dc aware",
+			in: CQLPolicyConfig{
+				HostSelection: CQLHostSelection{LocalDC: "dc1", TokenAware: true},
+				Retry:         CQLRetryPolicy{NumRetries: 2},
+				Reconnection:  CQLReconnectionPolicy{MaxInterval: time.Minute},
+			},
+			wantHost:      "*gocql.tokenAwareHostPolicy",
+			wantRetry:     "*gocql.ExponentialBackoffRetryPolicy",
+			wantReconnect: "*gocql.ExponentialReconnectionPolicy",
+		},
+		{
+			name: "should pass; rack aware downgrading consistency",
+			in: CQLPolicyConfig{
+				HostSelection: CQLHostSelection{LocalDC: "dc1", LocalRack: "rack1"},
+				Retry:         CQLRetryPolicy{DowngradeConsistency: []gocql.Consistency{gocql.LocalQuorum, gocql.LocalOne}},
+				Reconnection:  CQLReconnectionPolicy{MaxRetries: 10},
+			},
+			wantHost:      "*sql.rackAwarePolicy",
+			wantRetry:     "*sql.downgradingRetryPolicy",
+			wantReconnect: "*gocql.ConstantReconnectionPolicy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := gocql.NewCluster("127.0.0.1")
+			tt.in.applyCQL(cluster)
+			got := []string{
+				fmt.Sprintf("%T", cluster.PoolConfig.HostSelectionPolicy),
+				fmt.Sprintf("%T", cluster.RetryPolicy),
+				fmt.Sprintf("%T", cluster.ReconnectionPolicy),
+			}
+			if diff := cmp.Diff([]string{tt.wantHost, tt.wantRetry, tt.wantReconnect}, got); diff != "" {
+				t.Errorf("applyCQL() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCQLReconnectionPolicy_policy(t *testing.T) {
+	got := CQLReconnectionPolicy{MaxRetries: 10}.policy()
+	want := &gocql.ConstantReconnectionPolicy{MaxRetries: 10, Interval: time.Second}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("policy() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRackAwarePolicy_tier(t *testing.T) {
+	p := newRackAwarePolicy("dc1", "rack1")
+	tests := []struct {
+		dc, rack string
+		want     int
+	}{
+		{dc: "dc1", rack: "rack1", want: 0},
+		{dc: "dc1", rack: "rack2", want: 1},
+		{dc: "dc2", rack: "rack1", want: 2},
+	}
+	for _, tt := range tests {
+		if got := p.tier(tt.dc, tt.rack); got != tt.want {
+			t.Errorf("tier(%s, %s) = %d, want %d", tt.dc, tt.rack, got, tt.want)
+		}
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	host := func(ip string) *gocql.HostInfo {
+		return (&gocql.HostInfo{}).SetConnectAddress(net.ParseIP(ip))
+	}
+	rack := []*gocql.HostInfo{host("10.0.0.1"), host("10.0.0.2")}
+	dc := []*gocql.HostInfo{host("10.0.1.1")}
+	remote := []*gocql.HostInfo{host("10.1.0.1"), host("10.1.0.2")}
+
+	var got []string
+	next := roundRobin(1, rack, dc, remote)
+	for h := next(); h != nil; h = next() {
+		got = append(got, h.Info().ConnectAddress().String())
+	}
+	want := []string{"10.0.0.2", "10.0.0.1", "10.0.1.1", "10.1.0.2", "10.1.0.1"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("roundRobin() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDB_speculate(t *testing.T) {
+	db, err := NewConfig(WithCQLSpeculativeExecution(1, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		stmt string
+		want bool
+	}{
+		{name: "should pass; select", stmt: " select * from users", want: true},
+		{name: "should pass; insert", stmt: "INSERT INTO users (id) VALUES (?) IF NOT EXISTS", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := (&gocql.Session{}).Query(tt.stmt)
+			db.speculate(q)
+			if got := q.IsIdempotent(); got != tt.want {
+				t.Errorf("speculate() idempotent = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
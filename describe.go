@@ -0,0 +1,249 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/joematpal/go-sql/v2/table"
+)
+
+var (
+	ErrTableNotFound = errors.New("table not found")
+)
+
+const (
+	keyKindPartition  = "partition_key"
+	keyKindClustering = "clustering"
+)
+
+// describedColumn is a column as it is read from the schema tables
+type describedColumn struct {
+	Name     string
+	Type     string
+	Nullable bool
+	// Kind is partition_key or clustering for the key columns, sql primary keys are partition keys
+	Kind string
+	// Position is the order of the column in its key
+	Position int
+}
+
+// keyColumnsQuery joins the columns of a table to its primary key, the schema is an expression
+// for the current schema and the table name is bound twice
+const keyColumnsQuery = `SELECT c.column_name, c.%s, c.is_nullable, COALESCE(k.ordinal_position, 0)
+FROM information_schema.columns c
+LEFT JOIN (
+	SELECT kcu.column_name, kcu.ordinal_position
+	FROM information_schema.table_constraints tc
+	JOIN information_schema.key_column_usage kcu
+		ON kcu.constraint_name = tc.constraint_name AND kcu.table_schema = tc.table_schema AND kcu.table_name = tc.table_name
+	WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = %[2]s AND tc.table_name = ?
+) k ON k.column_name = c.column_name
+WHERE c.table_schema = %[2]s AND c.table_name = ?
+ORDER BY c.ordinal_position`
+
+// ListTables describes every table of the database, or of the keyspace for cql, ordered by name
+func (o *DB) ListTables(ctx context.Context) ([]table.Table, error) {
+	names, err := o.listTableNames(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+	sort.Strings(names)
+
+	out := make([]table.Table, 0, len(names))
+	for _, name := range names {
+		t, err := o.DescribeTable(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// DescribeTable reads the columns, their types and the keys of a table from the database.
+// ErrTableNotFound is returned when the table does not exist.
+func (o *DB) DescribeTable(ctx context.Context, name string) (table.Table, error) {
+	var (
+		columns []describedColumn
+		err     error
+	)
+	switch {
+	case o.cql != nil:
+		columns, err = o.describeCQLColumns(ctx, name)
+	case o.sql != nil:
+		columns, err = o.describeSQLColumns(ctx, name)
+	default:
+		return table.Table{}, ErrNoSourceConfigured
+	}
+	if err != nil {
+		return table.Table{}, fmt.Errorf("describe %s: %w", name, err)
+	}
+	if len(columns) == 0 {
+		return table.Table{}, fmt.Errorf("describe %s: %w", name, ErrTableNotFound)
+	}
+	return newDescribedTable(name, columns), nil
+}
+
+func (o *DB) listTableNames(ctx context.Context) ([]string, error) {
+	names := []string{}
+	switch o.DBSource {
+	case DBSource_cql:
+		if o.cql == nil {
+			return nil, ErrNoSourceConfigured
+		}
+		iter := o.cql.Session.Query(`SELECT table_name FROM system_schema.tables WHERE keyspace_name = ?`, o.DBName).WithContext(ctx).Iter()
+		var name string
+		for iter.Scan(&name) {
+			names = append(names, name)
+		}
+		return names, iter.Close()
+	}
+
+	if o.sql == nil {
+		return nil, ErrNoSourceConfigured
+	}
+	switch o.DBSource {
+	case DBSource_postgres:
+		return names, o.sql.SelectContext(ctx, &names, `SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'`)
+	case DBSource_mysql:
+		return names, o.sql.SelectContext(ctx, &names, `SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE'`)
+	case DBSource_sqlite:
+		return names, o.sql.SelectContext(ctx, &names, `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`)
+	}
+	return nil, ErrNoSourceConfigured
+}
+
+func (o *DB) describeSQLColumns(ctx context.Context, name string) ([]describedColumn, error) {
+	if o.sql == nil {
+		return nil, ErrNoSourceConfigured
+	}
+
+	var (
+		query string
+		args  = []interface{}{name, name}
+	)
+	switch o.DBSource {
+	case DBSource_postgres:
+		query = o.sql.Rebind(fmt.Sprintf(keyColumnsQuery, "data_type", "current_schema()"))
+	case DBSource_mysql:
+		query = fmt.Sprintf(keyColumnsQuery, "column_type", "DATABASE()")
+	case DBSource_sqlite:
+		query = `SELECT name, type, CASE WHEN "notnull" = 0 THEN 'YES' ELSE 'NO' END, pk FROM pragma_table_info(?)`
+		args = args[:1]
+	default:
+		return nil, ErrNoSourceConfigured
+	}
+
+	rows, err := o.sql.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []describedColumn{}
+	for rows.Next() {
+		var (
+			c        describedColumn
+			nullable string
+		)
+		if err := rows.Scan(&c.Name, &c.Type, &nullable, &c.Position); err != nil {
+			return nil, err
+		}
+		c.Nullable = nullable == "YES"
+		if c.Position > 0 {
+			c.Kind = keyKindPartition
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (o *DB) describeCQLColumns(ctx context.Context, name string) ([]describedColumn, error) {
+	iter := o.cql.Session.Query(
+		`SELECT column_name, type, kind, position FROM system_schema.columns WHERE keyspace_name = ? AND table_name = ?`,
+		o.DBName, name,
+	).WithContext(ctx).Iter()
+
+	out := []describedColumn{}
+	var c describedColumn
+	for iter.Scan(&c.Name, &c.Type, &c.Kind, &c.Position) {
+		// Only the key columns can't be null in cql
+		c.Nullable = c.Kind != keyKindPartition && c.Kind != keyKindClustering
+		// The positions of the keys are 0 based
+		c.Position++
+		out = append(out, c)
+	}
+	return out, iter.Close()
+}
+
+// newDescribedTable builds the table from its columns with the keys ordered by their position
+func newDescribedTable(name string, columns []describedColumn) table.Table {
+	t := table.New(name, map[string]struct{}{})
+	t.Definitions = map[string]table.Column{}
+
+	keys := map[string][]describedColumn{}
+	for _, c := range columns {
+		t.Columns[c.Name] = struct{}{}
+		t.Definitions[c.Name] = table.Column{Type: c.Type, Nullable: c.Nullable}
+		if c.Kind == keyKindPartition || c.Kind == keyKindClustering {
+			keys[c.Kind] = append(keys[c.Kind], c)
+		}
+	}
+
+	keyNames := func(kind string) []string {
+		cs := keys[kind]
+		if len(cs) == 0 {
+			return nil
+		}
+		sort.SliceStable(cs, func(i, j int) bool { return cs[i].Position < cs[j].Position })
+		out := make([]string, len(cs))
+		for i, c := range cs {
+			out[i] = c.Name
+		}
+		return out
+	}
+	return t.WithKeys(keyNames(keyKindPartition), keyNames(keyKindClustering)...)
+}
+
+// ForeignKeys returns the tables each table references by a foreign key, cql has none
+func (o *DB) ForeignKeys(ctx context.Context) (map[string][]string, error) {
+	var query string
+	switch o.DBSource {
+	case DBSource_cql:
+		return map[string][]string{}, nil
+	case DBSource_postgres:
+		query = `SELECT tc.table_name, ccu.table_name
+FROM information_schema.table_constraints tc
+JOIN information_schema.constraint_column_usage ccu
+	ON ccu.constraint_name = tc.constraint_name AND ccu.constraint_schema = tc.constraint_schema
+WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = current_schema()`
+	case DBSource_mysql:
+		query = `SELECT table_name, referenced_table_name FROM information_schema.key_column_usage
+WHERE table_schema = DATABASE() AND referenced_table_name IS NOT NULL`
+	case DBSource_sqlite:
+		query = `SELECT m.name, f."table" FROM sqlite_master m JOIN pragma_foreign_key_list(m.name) f WHERE m.type = 'table'`
+	default:
+		return nil, ErrNoSourceConfigured
+	}
+	if o.sql == nil {
+		return nil, ErrNoSourceConfigured
+	}
+
+	rows, err := o.sql.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	out := map[string][]string{}
+	for rows.Next() {
+		var from, to string
+		if err := rows.Scan(&from, &to); err != nil {
+			return nil, fmt.Errorf("foreign keys: %w", err)
+		}
+		out[from] = append(out[from], to)
+	}
+	return out, rows.Err()
+}
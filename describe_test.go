@@ -0,0 +1,102 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/joematpal/go-sql/v2/table"
+)
+
+func Test_newDescribedTable(t *testing.T) {
+	type args struct {
+		name    string
+		columns []describedColumn
+	}
+	tests := []struct {
+		name string
+		args args
+		want table.Table
+	}{
+		{
+			name: "should pass; sql primary key",
+			args: args{
+				name: "user_groups",
+				columns: []describedColumn{
+					{Name: "user_id", Type: "text", Kind: keyKindPartition, Position: 2},
+					{Name: "group_id", Type: "text", Kind: keyKindPartition, Position: 1},
+					{Name: "role", Type: "character varying", Nullable: true},
+				},
+			},
+			want: table.Table{
+				Name:    "user_groups",
+				Columns: map[string]struct{}{"user_id": {}, "group_id": {}, "role": {}},
+				Definitions: map[string]table.Column{
+					"user_id":  {Type: "text"},
+					"group_id": {Type: "text"},
+					"role":     {Type: "character varying", Nullable: true},
+				},
+				PartitionKey: []string{"group_id", "user_id"},
+			},
+		},
+		{
+			name: "should pass; cql partition and clustering keys",
+			args: args{
+				name: "events",
+				columns: []describedColumn{
+					{Name: "created_at", Type: "timestamp", Kind: keyKindClustering, Position: 2},
+					{Name: "id", Type: "uuid", Kind: keyKindClustering, Position: 1},
+					{Name: "tenant", Type: "text", Kind: keyKindPartition, Position: 1},
+					{Name: "body", Type: "text", Kind: "regular", Nullable: true},
+				},
+			},
+			want: table.Table{
+				Name:    "events",
+				Columns: map[string]struct{}{"created_at": {}, "id": {}, "tenant": {}, "body": {}},
+				Definitions: map[string]table.Column{
+					"created_at": {Type: "timestamp"},
+					"id":         {Type: "uuid"},
+					"tenant":     {Type: "text"},
+					"body":       {Type: "text", Nullable: true},
+				},
+				PartitionKey:  []string{"tenant"},
+				ClusteringKey: []string{"id", "created_at"},
+			},
+		},
+		{
+			name: "should pass; no keys",
+			args: args{
+				name:    "logs",
+				columns: []describedColumn{{Name: "line", Type: "TEXT", Nullable: true}},
+			},
+			want: table.Table{
+				Name:        "logs",
+				Columns:     map[string]struct{}{"line": {}},
+				Definitions: map[string]table.Column{"line": {Type: "TEXT", Nullable: true}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newDescribedTable(tt.args.name, tt.args.columns)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("newDescribedTable() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDB_ListTables_notConnected(t *testing.T) {
+	for _, source := range []DBSource{DBSource_postgres, DBSource_mysql, DBSource_sqlite, DBSource_cql} {
+		t.Run("should fail; "+string(source), func(t *testing.T) {
+			db := &DB{DBSource: source}
+			if _, err := db.ListTables(context.Background()); !errors.Is(err, ErrNoSourceConfigured) {
+				t.Errorf("DB.ListTables() error = %v, want %v", err, ErrNoSourceConfigured)
+			}
+			if _, err := db.DescribeTable(context.Background(), "users"); !errors.Is(err, ErrNoSourceConfigured) {
+				t.Errorf("DB.DescribeTable() error = %v, want %v", err, ErrNoSourceConfigured)
+			}
+		})
+	}
+}
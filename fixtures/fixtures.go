@@ -0,0 +1,268 @@
+// Package fixtures seeds a database with rows read from yaml or json files keyed by table name.
+//
+//	users:
+//	  - id: 1
+//	    firstName: joe
+//	groups:
+//	  - id: 1
+//	    name: admins
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	sql "github.com/joematpal/go-sql/v2"
+	"github.com/joematpal/go-sql/v2/table"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrUnknownFormat = errors.New("fixture format is not yaml or json")
+	ErrCycle         = errors.New("foreign keys of the fixture tables form a cycle")
+)
+
+// Row is a row of a table, the names are mapped to columns by the mapper of the DB
+type Row map[string]interface{}
+
+// Fixtures are the rows of each table
+type Fixtures map[string][]Row
+
+// Merge appends the rows of other to the rows of f
+func (f Fixtures) Merge(other Fixtures) {
+	for name, rows := range other {
+		f[name] = append(f[name], rows...)
+	}
+}
+
+// Tables returns the names of the tables in a stable order
+func (f Fixtures) Tables() []string {
+	out := make([]string, 0, len(f))
+	for name := range f {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Parse reads the fixtures of a file, the extension of the name picks yaml (.yaml, .yml) or json
+func Parse(name string, b []byte) (Fixtures, error) {
+	out := Fixtures{}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(b, &out); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		if err := dec.Decode(&out); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	default:
+		return nil, fmt.Errorf("%s: %w", name, ErrUnknownFormat)
+	}
+	for _, rows := range out {
+		for _, row := range rows {
+			for k, v := range row {
+				row[k] = normalize(v)
+			}
+		}
+	}
+	return out, nil
+}
+
+// normalize turns json numbers into int64 or float64 so the drivers can bind them
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalize(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalize(e)
+		}
+	}
+	return v
+}
+
+// ReadFS reads and merges the files of fsys that match the patterns,
+// every yaml and json file of the root when no pattern is passed
+func ReadFS(fsys fs.FS, patterns ...string) (Fixtures, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"*.yaml", "*.yml", "*.json"}
+	}
+
+	out := Fixtures{}
+	for _, pattern := range patterns {
+		names, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			b, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return nil, err
+			}
+			f, err := Parse(name, b)
+			if err != nil {
+				return nil, err
+			}
+			out.Merge(f)
+		}
+	}
+	return out, nil
+}
+
+// ReadFiles reads and merges the files
+func ReadFiles(paths ...string) (Fixtures, error) {
+	out := Fixtures{}
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		f, err := Parse(p, b)
+		if err != nil {
+			return nil, err
+		}
+		out.Merge(f)
+	}
+	return out, nil
+}
+
+// LoadFS reads the fixtures of fsys with ReadFS and loads them
+func LoadFS(ctx context.Context, db *sql.DB, fsys fs.FS, patterns ...string) error {
+	f, err := ReadFS(fsys, patterns...)
+	if err != nil {
+		return err
+	}
+	return Load(ctx, db, f)
+}
+
+// Load clears the tables of the fixtures and inserts their rows. The tables are cleared before
+// the tables they reference by a foreign key and the rows are inserted after them.
+// Tables without rows are only cleared. cql tables are truncated.
+func Load(ctx context.Context, db *sql.DB, f Fixtures) error {
+	foreignKeys, err := db.ForeignKeys(ctx)
+	if err != nil {
+		return err
+	}
+	order, err := insertOrder(f.Tables(), foreignKeys)
+	if err != nil {
+		return err
+	}
+
+	d := db.DBSource.Dialect()
+	for i := len(order) - 1; i >= 0; i-- {
+		if err := db.ExecStmtContext(ctx, clearStmt(d, order[i])); err != nil {
+			return fmt.Errorf("clear %s: %w", order[i], err)
+		}
+	}
+	for _, name := range order {
+		for i, row := range f[name] {
+			stmt, names, args, err := insert(db, name, row)
+			if err != nil {
+				return fmt.Errorf("insert %s row %d: %w", name, i, err)
+			}
+			if err := db.ExecMapContext(ctx, stmt, names, args); err != nil {
+				return fmt.Errorf("insert %s row %d: %w", name, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func clearStmt(d table.Dialect, name string) string {
+	if d == table.Dialect_cql {
+		return "TRUNCATE " + d.Quote(name)
+	}
+	return "DELETE FROM " + d.Quote(name)
+}
+
+// insert maps the names of the row to columns, sql has no collection types so maps and lists are stored as json
+func insert(db *sql.DB, name string, row Row) (string, []string, map[string]interface{}, error) {
+	d := db.DBSource.Dialect()
+	columns := map[string]struct{}{}
+	args := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		column := db.MapName(k)
+		columns[column] = struct{}{}
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			if d != table.Dialect_cql {
+				b, err := json.Marshal(v)
+				if err != nil {
+					return "", nil, nil, err
+				}
+				v = string(b)
+			}
+		}
+		args[column] = v
+	}
+	stmt, names, err := table.New(name, columns).Insert(d)
+	return stmt, names, args, err
+}
+
+// insertOrder sorts the tables so a table comes after the tables it references,
+// references to tables without fixtures and to the table itself are ignored
+func insertOrder(tables []string, foreignKeys map[string][]string) ([]string, error) {
+	want := map[string]bool{}
+	for _, name := range tables {
+		want[name] = true
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	out := make([]string, 0, len(tables))
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrCycle, name)
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		refs := append([]string(nil), foreignKeys[name]...)
+		sort.Strings(refs)
+		for _, ref := range refs {
+			if ref == name || !want[ref] {
+				continue
+			}
+			if err := visit(ref); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+		out = append(out, name)
+		return nil
+	}
+	for _, name := range tables {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,97 @@
+//go:build sqlite
+// +build sqlite
+
+package fixtures
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	sql "github.com/joematpal/go-sql/v2"
+)
+
+// TestLoad_sqlite loads a child table that sorts before its parent, the triggers log the order
+// of the deletes and the inserts and the foreign key fails the load when the order is wrong
+func TestLoad_sqlite(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.New(
+		sql.WithDBSource("sqlite"),
+		sql.WithDBName(filepath.Join(t.TempDir(), "test.db")),
+		sql.WithRegistry(sql.NewRegistry()),
+		// foreign_keys is a pragma of the connection
+		sql.WithPool(sql.PoolConfig{MaxOpenConns: 1, MaxIdleConns: 1}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for _, stmt := range []string{
+		`PRAGMA foreign_keys = ON`,
+		`CREATE TABLE users (id int PRIMARY KEY, name text)`,
+		`CREATE TABLE memberships (user_id int REFERENCES users (id), group_name text)`,
+		`CREATE TABLE events (id integer PRIMARY KEY AUTOINCREMENT, event text)`,
+		`CREATE TRIGGER users_delete AFTER DELETE ON users BEGIN INSERT INTO events (event) VALUES ('delete users'); END`,
+		`CREATE TRIGGER users_insert AFTER INSERT ON users BEGIN INSERT INTO events (event) VALUES ('insert users'); END`,
+		`CREATE TRIGGER memberships_delete AFTER DELETE ON memberships BEGIN INSERT INTO events (event) VALUES ('delete memberships'); END`,
+		`CREATE TRIGGER memberships_insert AFTER INSERT ON memberships BEGIN INSERT INTO events (event) VALUES ('insert memberships'); END`,
+		`INSERT INTO users (id, name) VALUES (9, 'old')`,
+		`INSERT INTO memberships (user_id, group_name) VALUES (9, 'old')`,
+		`DELETE FROM events`,
+	} {
+		if err := db.ExecStmtContext(ctx, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	foreignKeys, err := db.ForeignKeys(ctx)
+	if err != nil {
+		t.Fatalf("DB.ForeignKeys() error = %v", err)
+	}
+	if diff := cmp.Diff(map[string][]string{"memberships": {"users"}}, foreignKeys); diff != "" {
+		t.Errorf("DB.ForeignKeys() mismatch (-want +got):\n%s", diff)
+	}
+
+	f := Fixtures{
+		"memberships": {{"userId": 1, "groupName": "admins"}},
+		"users":       {{"id": 1, "name": "joe"}},
+	}
+	var enforced int
+	if err := db.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&enforced); err != nil || enforced != 1 {
+		t.Fatalf("PRAGMA foreign_keys = %d, %v, want 1", enforced, err)
+	}
+	if err := Load(ctx, db, f); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	var events []string
+	rows, err := db.QueryRows(ctx, "SELECT event FROM events ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var event string
+		if err := rows.Scan(&event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"delete memberships", "delete users", "insert users", "insert memberships"}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Errorf("Load() order mismatch (-want +got):\n%s", diff)
+	}
+
+	var name string
+	if err := db.QueryRowContext(ctx, "SELECT u.name FROM memberships m JOIN users u ON u.id = m.user_id WHERE m.group_name = 'admins'").Scan(&name); err != nil {
+		t.Fatalf("loaded rows: %v", err)
+	}
+	if name != "joe" {
+		t.Errorf("loaded user = %q, want %q", name, "joe")
+	}
+}
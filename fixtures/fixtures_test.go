@@ -0,0 +1,128 @@
+package fixtures
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	sql "github.com/joematpal/go-sql/v2"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		want    Fixtures
+		wantErr error
+	}{
+		{
+			name: "should pass; yaml",
+			file: "users.yaml",
+			data: "users:\n  - id: 1\n    firstName: joe\n    tags: [a, b]\n",
+			want: Fixtures{"users": {{"id": 1, "firstName": "joe", "tags": []interface{}{"a", "b"}}}},
+		},
+		{
+			name: "should pass; json numbers",
+			file: "users.json",
+			data: `{"users": [{"id": 1, "score": 1.5, "settings": {"limit": 10}}]}`,
+			want: Fixtures{"users": {{"id": int64(1), "score": 1.5, "settings": map[string]interface{}{"limit": int64(10)}}}},
+		},
+		{
+			name:    "should fail; unknown extension",
+			file:    "users.txt",
+			wantErr: ErrUnknownFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.file, []byte(tt.data))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); tt.wantErr == nil && diff != "" {
+				t.Errorf("Parse() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.yaml":    {Data: []byte("users:\n  - id: 1\n")},
+		"b.json":    {Data: []byte(`{"users": [{"id": 2}], "groups": [{"id": 3}]}`)},
+		"README.md": {Data: []byte("not a fixture")},
+	}
+	got, err := ReadFS(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Fixtures{
+		"users":  {{"id": 1}, {"id": int64(2)}},
+		"groups": {{"id": int64(3)}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadFS() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_insertOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		tables      []string
+		foreignKeys map[string][]string
+		want        []string
+		wantErr     error
+	}{
+		{
+			name:        "should pass; references first",
+			tables:      []string{"groups", "user_groups", "users"},
+			foreignKeys: map[string][]string{"user_groups": {"users", "groups"}},
+			want:        []string{"groups", "users", "user_groups"},
+		},
+		{
+			name:        "should pass; ignores self and missing references",
+			tables:      []string{"users", "comments"},
+			foreignKeys: map[string][]string{"comments": {"comments", "posts", "users"}},
+			want:        []string{"users", "comments"},
+		},
+		{
+			name:        "should fail; cycle",
+			tables:      []string{"a", "b"},
+			foreignKeys: map[string][]string{"a": {"b"}, "b": {"a"}},
+			wantErr:     ErrCycle,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := insertOrder(tt.tables, tt.foreignKeys)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("insertOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); tt.wantErr == nil && diff != "" {
+				t.Errorf("insertOrder() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_insert(t *testing.T) {
+	db, err := sql.New(sql.WithDBSource("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt, names, args, err := insert(db, "users", Row{"userId": 1, "settings": map[string]interface{}{"a": 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `INSERT INTO "users" ("settings", "user_id") VALUES (?, ?)`; stmt != want {
+		t.Errorf("insert() stmt = %q, want %q", stmt, want)
+	}
+	if diff := cmp.Diff([]string{"settings", "user_id"}, names); diff != "" {
+		t.Errorf("insert() names mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]interface{}{"settings": `{"a":1}`, "user_id": 1}, args); diff != "" {
+		t.Errorf("insert() args mismatch (-want +got):\n%s", diff)
+	}
+}
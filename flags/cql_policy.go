@@ -0,0 +1,130 @@
+package flags
+
+import (
+	"strings"
+
+	"github.com/gocql/gocql"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	DBCQLLocalDC                  = "db-cql-local-dc"
+	DBCQLLocalRack                = "db-cql-local-rack"
+	DBCQLTokenAware               = "db-cql-token-aware"
+	DBCQLShuffleReplicas          = "db-cql-shuffle-replicas"
+	DBCQLNonLocalReplicasFallback = "db-cql-non-local-replicas-fallback"
+	DBCQLRetries                  = "db-cql-retries"
+	DBCQLRetryMinBackoff          = "db-cql-retry-min-backoff"
+	DBCQLRetryMaxBackoff          = "db-cql-retry-max-backoff"
+	DBCQLDowngradeConsistency     = "db-cql-downgrade-consistency"
+	DBCQLSpeculativeAttempts      = "db-cql-speculative-attempts"
+	DBCQLSpeculativeDelay         = "db-cql-speculative-delay"
+	DBCQLReconnectMaxRetries      = "db-cql-reconnect-max-retries"
+	DBCQLReconnectInterval        = "db-cql-reconnect-initial-interval"
+	DBCQLReconnectMaxInterval     = "db-cql-reconnect-max-interval"
+)
+
+// CQLPolicyFlags are the host selection, retry, speculative execution and reconnection policies of cql
+var CQLPolicyFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    DBCQLLocalDC,
+		Usage:   "the datacenter the queries are sent to first",
+		EnvVars: flagNamesToEnv(DBCQLLocalDC),
+	},
+	&cli.StringFlag{
+		Name:    DBCQLLocalRack,
+		Usage:   "the rack of db-cql-local-dc the queries are sent to first",
+		EnvVars: flagNamesToEnv(DBCQLLocalRack),
+	},
+	&cli.BoolFlag{
+		Name:    DBCQLTokenAware,
+		Usage:   "send the queries to the replicas of their partition first",
+		EnvVars: flagNamesToEnv(DBCQLTokenAware),
+	},
+	&cli.BoolFlag{
+		Name:    DBCQLShuffleReplicas,
+		Usage:   "spread the queries of a partition over its replicas, needs db-cql-token-aware",
+		EnvVars: flagNamesToEnv(DBCQLShuffleReplicas),
+	},
+	&cli.BoolFlag{
+		Name:    DBCQLNonLocalReplicasFallback,
+		Usage:   "try the replicas in the other datacenters before the local hosts, needs db-cql-token-aware",
+		EnvVars: flagNamesToEnv(DBCQLNonLocalReplicasFallback),
+	},
+	&cli.IntFlag{
+		Name:    DBCQLRetries,
+		Usage:   "how many times a failed query is retried on the next host",
+		EnvVars: flagNamesToEnv(DBCQLRetries),
+	},
+	&cli.DurationFlag{
+		Name:    DBCQLRetryMinBackoff,
+		Usage:   "the first sleep between the retries, it doubles up to db-cql-retry-max-backoff (default 100ms)",
+		EnvVars: flagNamesToEnv(DBCQLRetryMinBackoff),
+	},
+	&cli.DurationFlag{
+		Name:    DBCQLRetryMaxBackoff,
+		Usage:   "the longest sleep between the retries (default 10s)",
+		EnvVars: flagNamesToEnv(DBCQLRetryMaxBackoff),
+	},
+	&cli.GenericFlag{
+		Name:    DBCQLDowngradeConsistency,
+		Usage:   "the consistencies of the retries in order, ie. LOCAL_QUORUM,LOCAL_ONE",
+		Value:   &consistencies{},
+		EnvVars: flagNamesToEnv(DBCQLDowngradeConsistency),
+	},
+	&cli.IntFlag{
+		Name:    DBCQLSpeculativeAttempts,
+		Usage:   "how many more hosts a read is sent to when the hosts before it are slow",
+		EnvVars: flagNamesToEnv(DBCQLSpeculativeAttempts),
+	},
+	&cli.DurationFlag{
+		Name:    DBCQLSpeculativeDelay,
+		Usage:   "how long a read waits for a host before it is sent to the next one",
+		EnvVars: flagNamesToEnv(DBCQLSpeculativeDelay),
+	},
+	&cli.IntFlag{
+		Name:    DBCQLReconnectMaxRetries,
+		Usage:   "how many times a host is reconnected to before it is marked down (default 3)",
+		EnvVars: flagNamesToEnv(DBCQLReconnectMaxRetries),
+	},
+	&cli.DurationFlag{
+		Name:    DBCQLReconnectInterval,
+		Usage:   "the wait between the reconnections (default 1s)",
+		EnvVars: flagNamesToEnv(DBCQLReconnectInterval),
+	},
+	&cli.DurationFlag{
+		Name:    DBCQLReconnectMaxInterval,
+		Usage:   "makes the waits between the reconnections grow exponentially up to it",
+		EnvVars: flagNamesToEnv(DBCQLReconnectMaxInterval),
+	},
+}
+
+// consistencies is the value of the db-cql-downgrade-consistency flag, the levels are checked when it is parsed
+type consistencies []gocql.Consistency
+
+// Set replaces the levels, the flag is a comma separated list
+func (c *consistencies) Set(value string) error {
+	*c = nil
+	for _, level := range strings.Split(value, ",") {
+		if level = strings.TrimSpace(level); level == "" {
+			continue
+		}
+		consistency, err := gocql.ParseConsistencyWrapper(strings.ToUpper(level))
+		if err != nil {
+			return err
+		}
+		*c = append(*c, consistency)
+	}
+	return nil
+}
+
+func (c *consistencies) String() string {
+	if c == nil {
+		return ""
+	}
+	levels := make([]string, len(*c))
+	for i, consistency := range *c {
+		levels[i] = consistency.String()
+	}
+	return strings.Join(levels, ",")
+}
@@ -0,0 +1,139 @@
+package flags
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sigv4-auth-cassandra-gocql-driver-plugin/sigv4"
+	sql "github.com/joematpal/go-sql/v2"
+	"github.com/urfave/cli/v2"
+)
+
+// defaultPorts are used when the db-port flag is not set, its default is the postgres port
+var defaultPorts = map[sql.DBSource]string{
+	sql.DBSource_mysql: "3306",
+	sql.DBSource_cql:   "9042",
+}
+
+// Source returns the db-source flag and falls back to db-type
+func Source(c *cli.Context) sql.DBSource {
+	if source := c.String(DBSource); source != "" {
+		return sql.DBSource(source)
+	}
+	return sql.DBSource(c.String(DBType))
+}
+
+// DBOptions returns the options for sql.New from the DBFlags.
+// For cql the sigv4 authenticator is used when the aws-region of the AWSCQLAuthFlags is set,
+// with the aws credentials of the flags or else of the default aws credential chain.
+func DBOptions(c *cli.Context) []sql.Option {
+	source := Source(c)
+
+	port := c.String(DBPort)
+	if p, ok := defaultPorts[source]; ok && !c.IsSet(DBPort) {
+		port = p
+	}
+
+	opts := []sql.Option{
+		sql.WithDBSource(source.String()),
+		sql.WithUser(c.String(DBUser)),
+		sql.WithPassword(c.String(DBPass)),
+		sql.WithDBName(c.String(DBName)),
+		sql.WithPort(port),
+		sql.WithMigrate(c.Bool(Migrate)),
+		sql.WithMigratePath(c.String(MigratePath)),
+		sql.WithMigrateDryRun(c.Bool(MigrateDryRun)),
+		// The flags that are not set are zero and keep the defaults of the pool
+		sql.WithPool(sql.PoolConfig{
+			MaxOpenConns:      c.Int(DBMaxOpenConns),
+			MaxIdleConns:      c.Int(DBMaxIdleConns),
+			ConnMaxLifetime:   c.Duration(DBConnMaxLifetime),
+			ConnMaxIdleTime:   c.Duration(DBConnMaxIdleTime),
+			NumConns:          c.Int(DBNumConns),
+			ReconnectInterval: c.Duration(DBReconnectInterval),
+		}),
+	}
+	for _, host := range strings.Split(c.String(DBHosts), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			opts = append(opts, sql.WithHost(host))
+		}
+	}
+	if c.Bool(MigrateDialectDirs) {
+		opts = append(opts, sql.WithMigrateDialectDirs(nil, ""))
+	}
+	if ca := c.String(DBCertificateAuthority); ca != "" {
+		opts = append(opts, sql.WithCertificateAuthority(ca))
+	}
+	if key := c.String(DBPageTokenKey); key != "" {
+		opts = append(opts, sql.WithPageTokenKey([]byte(key)))
+	}
+	if c.Bool(DBTLS) || c.String(DBTLSMode) != "" {
+		opts = append(opts, sql.WithTLS(sql.TLSConfig{
+			CA:         c.String(DBCertificateAuthority),
+			Cert:       c.String(DBPubCert),
+			Key:        c.String(DBPrivCert),
+			ServerName: c.String(DBTLSServerName),
+			Mode:       sql.TLSMode(c.String(DBTLSMode)),
+		}))
+	}
+
+	if source == sql.DBSource_cql && c.String(AWSRegion) != "" {
+		opts = append(opts, sql.WithAuthenticator(awsAuthenticator(c)))
+	}
+	if source == sql.DBSource_cql {
+		opts = append(opts, cqlPolicyOptions(c)...)
+	}
+	return opts
+}
+
+// cqlPolicyOptions returns the options of the CQLPolicyFlags that are set, the others keep the defaults of gocql
+func cqlPolicyOptions(c *cli.Context) []sql.Option {
+	var opts []sql.Option
+	selection := sql.CQLHostSelection{
+		LocalDC:                  c.String(DBCQLLocalDC),
+		LocalRack:                c.String(DBCQLLocalRack),
+		TokenAware:               c.Bool(DBCQLTokenAware),
+		ShuffleReplicas:          c.Bool(DBCQLShuffleReplicas),
+		NonLocalReplicasFallback: c.Bool(DBCQLNonLocalReplicasFallback),
+	}
+	if selection != (sql.CQLHostSelection{}) {
+		opts = append(opts, sql.WithCQLHostSelection(selection))
+	}
+
+	retry := sql.CQLRetryPolicy{
+		NumRetries: c.Int(DBCQLRetries),
+		MinBackoff: c.Duration(DBCQLRetryMinBackoff),
+		MaxBackoff: c.Duration(DBCQLRetryMaxBackoff),
+	}
+	if levels, ok := c.Generic(DBCQLDowngradeConsistency).(*consistencies); ok && levels != nil {
+		retry.DowngradeConsistency = *levels
+	}
+	if retry.NumRetries != 0 || len(retry.DowngradeConsistency) > 0 {
+		opts = append(opts, sql.WithCQLRetryPolicy(retry))
+	}
+
+	if attempts := c.Int(DBCQLSpeculativeAttempts); attempts != 0 {
+		opts = append(opts, sql.WithCQLSpeculativeExecution(attempts, c.Duration(DBCQLSpeculativeDelay)))
+	}
+
+	reconnection := sql.CQLReconnectionPolicy{
+		MaxRetries:      c.Int(DBCQLReconnectMaxRetries),
+		InitialInterval: c.Duration(DBCQLReconnectInterval),
+		MaxInterval:     c.Duration(DBCQLReconnectMaxInterval),
+	}
+	if reconnection != (sql.CQLReconnectionPolicy{}) {
+		opts = append(opts, sql.WithCQLReconnectionPolicy(reconnection))
+	}
+	return opts
+}
+
+func awsAuthenticator(c *cli.Context) sigv4.AwsAuthenticator {
+	if c.String(AWSAccessKeyID) == "" {
+		return sigv4.NewAwsAuthenticatorWithRegion(c.String(AWSRegion))
+	}
+	return sigv4.AwsAuthenticator{
+		Region:          c.String(AWSRegion),
+		AccessKeyId:     c.String(AWSAccessKeyID),
+		SecretAccessKey: c.String(AWSSecretAccessKey),
+		SessionToken:    c.String(AWSSessionToken),
+	}
+}
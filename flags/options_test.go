@@ -0,0 +1,157 @@
+package flags
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	sql "github.com/joematpal/go-sql/v2"
+	"github.com/urfave/cli/v2"
+)
+
+func TestDBOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    []sql.Option
+		wantErr bool
+	}{
+		{
+			name: "should pass; postgres defaults",
+			args: []string{"--db-source", "postgres", "--db-name", "test_db"},
+			want: []sql.Option{
+				sql.WithDBSource("postgres"),
+				sql.WithDBName("test_db"),
+				sql.WithPort("5432"),
+			},
+		},
+		{
+			name: "should pass; db-type is the fallback of db-source",
+			args: []string{"--db-type", "mysql", "--db-hosts", "10.0.0.1, 10.0.0.2"},
+			want: []sql.Option{
+				sql.WithDBSource("mysql"),
+				sql.WithPort("3306"),
+				sql.WithHost("10.0.0.1"),
+				sql.WithHost("10.0.0.2"),
+			},
+		},
+		{
+			name: "should pass; the port flag wins over the default port",
+			args: []string{"--db-source", "cql", "--db-port", "19042"},
+			want: []sql.Option{
+				sql.WithDBSource("cql"),
+				sql.WithPort("19042"),
+			},
+		},
+		{
+			name: "should pass; migrations",
+			args: []string{"--db-source", "postgres", "--migrate", "--migrate-path", "database", "--migrate-dialect-dirs", "--migrate-dry-run"},
+			want: []sql.Option{
+				sql.WithDBSource("postgres"),
+				sql.WithPort("5432"),
+				sql.WithMigrate(true),
+				sql.WithMigratePath("database"),
+				sql.WithMigrateDialectDirs(nil, ""),
+				sql.WithMigrateDryRun(true),
+			},
+		},
+		{
+			name: "should pass; pool",
+			args: []string{"--db-source", "postgres", "--db-max-open-conns", "20", "--db-conn-max-lifetime", "1h"},
+			want: []sql.Option{
+				sql.WithDBSource("postgres"),
+				sql.WithPort("5432"),
+				sql.WithPool(sql.PoolConfig{MaxOpenConns: 20, ConnMaxLifetime: time.Hour}),
+			},
+		},
+		{
+			name: "should pass; tls",
+			args: []string{"--db-source", "postgres", "--db-tls-mode", "verify-full", "--db-tls-server-name", "db.internal"},
+			want: []sql.Option{
+				sql.WithDBSource("postgres"),
+				sql.WithPort("5432"),
+				sql.WithTLS(sql.TLSConfig{Mode: sql.TLSModeVerifyFull, ServerName: "db.internal"}),
+			},
+		},
+		{
+			name: "should pass; page token key",
+			args: []string{"--db-source", "postgres", "--db-page-token-key", "secret"},
+			want: []sql.Option{
+				sql.WithDBSource("postgres"),
+				sql.WithPort("5432"),
+				sql.WithPageTokenKey([]byte("secret")),
+			},
+		},
+		{
+			name: "should pass; cql policies",
+			args: []string{
+				"--db-source", "cql",
+				"--db-cql-local-dc", "dc1", "--db-cql-token-aware",
+				"--db-cql-retries", "3", "--db-cql-downgrade-consistency", "local_quorum,local_one",
+				"--db-cql-speculative-attempts", "2", "--db-cql-speculative-delay", "50ms",
+				"--db-cql-reconnect-max-retries", "5",
+			},
+			want: []sql.Option{
+				sql.WithDBSource("cql"),
+				sql.WithPort("9042"),
+				sql.WithCQLHostSelection(sql.CQLHostSelection{LocalDC: "dc1", TokenAware: true}),
+				sql.WithCQLRetryPolicy(sql.CQLRetryPolicy{NumRetries: 3, DowngradeConsistency: []gocql.Consistency{gocql.LocalQuorum, gocql.LocalOne}}),
+				sql.WithCQLSpeculativeExecution(2, 50*time.Millisecond),
+				sql.WithCQLReconnectionPolicy(sql.CQLReconnectionPolicy{MaxRetries: 5}),
+			},
+		},
+		{
+			name: "should pass; cql policies are ignored by the sql sources",
+			args: []string{"--db-source", "postgres", "--db-cql-local-dc", "dc1"},
+			want: []sql.Option{
+				sql.WithDBSource("postgres"),
+				sql.WithPort("5432"),
+			},
+		},
+		{
+			name:    "should fail; unknown consistency",
+			args:    []string{"--db-source", "cql", "--db-cql-downgrade-consistency", "most"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []sql.Option
+			app := &cli.App{
+				Writer:    io.Discard,
+				ErrWriter: io.Discard,
+				Flags:     append(append([]cli.Flag{}, DBFlags...), CQLPolicyFlags...),
+				Action: func(c *cli.Context) error {
+					got = DBOptions(c)
+					return nil
+				},
+			}
+			err := app.Run(append([]string{"test"}, tt.args...))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("app.Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			gotDB, err := sql.NewConfig(got...)
+			if err != nil {
+				t.Fatalf("sql.NewConfig() error = %v", err)
+			}
+			wantDB, err := sql.NewConfig(tt.want...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := []cmp.Option{
+				cmpopts.IgnoreUnexported(sql.DB{}),
+				cmpopts.EquateEmpty(),
+			}
+			if diff := cmp.Diff(wantDB, gotDB, opts...); diff != "" {
+				t.Errorf("DBOptions() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -9,14 +9,25 @@ const (
 	DBName  = "db-name"
 	DBPass  = "db-pass"
 	DBPort  = "db-port"
-	// TODO: we need to find a way to implement TLS for the db drivers we support
+	// TLS for every db source, see sql.WithTLS
 	DBTLS                  = "db-tls"
+	DBTLSMode              = "db-tls-mode"
+	DBTLSServerName        = "db-tls-server-name"
 	DBCertificateAuthority = "db-ca-cert"
 	DBPubCert              = "db-pub-cert"
 	DBPrivCert             = "db-priv-cert"
 	Migrate                = "migrate"
 	MigratePath            = "migrate-path"
+	MigrateDryRun          = "migrate-dry-run"
+	MigrateDialectDirs     = "migrate-dialect-dirs"
 	DBSource               = "db-source"
+	DBMaxOpenConns         = "db-max-open-conns"
+	DBMaxIdleConns         = "db-max-idle-conns"
+	DBConnMaxLifetime      = "db-conn-max-lifetime"
+	DBConnMaxIdleTime      = "db-conn-max-idle-time"
+	DBNumConns             = "db-num-conns"
+	DBReconnectInterval    = "db-reconnect-interval"
+	DBPageTokenKey         = "db-page-token-key"
 )
 
 var DBFlags = []cli.Flag{
@@ -50,11 +61,21 @@ var DBFlags = []cli.Flag{
 		Name:    Migrate,
 		EnvVars: flagNamesToEnv(Migrate),
 	},
+	&cli.BoolFlag{
+		Name:    MigrateDryRun,
+		Usage:   "log the pending migrations instead of applying them",
+		EnvVars: flagNamesToEnv(MigrateDryRun),
+	},
 	&cli.StringFlag{
 		Name:    MigratePath,
 		Value:   "database/sql",
 		EnvVars: flagNamesToEnv(MigratePath),
 	},
+	&cli.BoolFlag{
+		Name:    MigrateDialectDirs,
+		Usage:   "read the migrations from the directory of the db source under migrate-path, ie. psql for postgres",
+		EnvVars: flagNamesToEnv(MigrateDialectDirs),
+	},
 	&cli.StringFlag{
 		Name:    DBSource,
 		Value:   "",
@@ -62,10 +83,66 @@ var DBFlags = []cli.Flag{
 	},
 	&cli.BoolFlag{
 		Name:    DBTLS,
+		Usage:   "encrypt the connection, the server certificate is verified unless db-tls-mode is set",
 		EnvVars: flagNamesToEnv(DBTLS),
 	},
+	&cli.StringFlag{
+		Name:    DBTLSMode,
+		Usage:   "disable, require, verify-ca or verify-full, setting it enables tls",
+		EnvVars: flagNamesToEnv(DBTLSMode),
+	},
+	&cli.StringFlag{
+		Name:    DBTLSServerName,
+		Usage:   "the name the server certificate is verified against, the host by default",
+		EnvVars: flagNamesToEnv(DBTLSServerName),
+	},
 	&cli.StringFlag{
 		Name:    DBCertificateAuthority,
 		EnvVars: flagNamesToEnv((DBCertificateAuthority)),
 	},
+	&cli.StringFlag{
+		Name:    DBPubCert,
+		Usage:   "path of the client certificate for mutual tls",
+		EnvVars: flagNamesToEnv(DBPubCert),
+	},
+	&cli.StringFlag{
+		Name:    DBPrivCert,
+		Usage:   "path of the key of the client certificate",
+		EnvVars: flagNamesToEnv(DBPrivCert),
+	},
+	&cli.IntFlag{
+		Name:    DBMaxOpenConns,
+		Usage:   "max open sql connections, -1 for no limit (default 25)",
+		EnvVars: flagNamesToEnv(DBMaxOpenConns),
+	},
+	&cli.IntFlag{
+		Name:    DBMaxIdleConns,
+		Usage:   "max idle sql connections (default 10)",
+		EnvVars: flagNamesToEnv(DBMaxIdleConns),
+	},
+	&cli.DurationFlag{
+		Name:    DBConnMaxLifetime,
+		Usage:   "max lifetime of a sql connection, -1ns for no limit (default 30m, no limit for sqlite)",
+		EnvVars: flagNamesToEnv(DBConnMaxLifetime),
+	},
+	&cli.DurationFlag{
+		Name:    DBConnMaxIdleTime,
+		Usage:   "max idle time of a sql connection, -1ns for no limit (default 5m, no limit for sqlite)",
+		EnvVars: flagNamesToEnv(DBConnMaxIdleTime),
+	},
+	&cli.IntFlag{
+		Name:    DBNumConns,
+		Usage:   "cql connections per host (default 2)",
+		EnvVars: flagNamesToEnv(DBNumConns),
+	},
+	&cli.DurationFlag{
+		Name:    DBReconnectInterval,
+		Usage:   "how often cql reconnects to the hosts that are down (default 1m)",
+		EnvVars: flagNamesToEnv(DBReconnectInterval),
+	},
+	&cli.StringFlag{
+		Name:    DBPageTokenKey,
+		Usage:   "secret that encrypts the page tokens, the same on every instance",
+		EnvVars: flagNamesToEnv(DBPageTokenKey),
+	},
 }
@@ -0,0 +1,121 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"reflect"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/scylladb/gocqlx/v2"
+)
+
+// SelectT runs a select and returns the rows as a slice of T
+func SelectT[T any](ctx context.Context, db *DB, stmt string, names []string, args interface{}) ([]T, error) {
+	var out []T
+	if err := db.SelectContext(ctx, &out, stmt, names, args); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetT runs a select and returns the first row as T
+func GetT[T any](ctx context.Context, db *DB, stmt string, names []string, args interface{}) (T, error) {
+	var out T
+	if err := db.GetContext(ctx, &out, stmt, names, args); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// Iter streams the rows of a select one at a time instead of loading all of them into memory.
+// The query runs when the sequence is ranged over, any error is yielded as the last value.
+//
+//	for user, err := range sql.Iter[User](ctx, db, stmt, names, args) {
+//		if err != nil {
+//			return err
+//		}
+//	}
+func Iter[T any](ctx context.Context, db *DB, stmt string, names []string, args interface{}) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		structScan := isStructScannable(reflect.TypeOf(zero))
+
+		rows, err := db.bindQuery(ctx, stmt, names, args)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			v, dest := newScanDest[T]()
+			if structScan {
+				err = rows.StructScan(dest)
+			} else {
+				err = rows.Scan(dest)
+			}
+			if err != nil {
+				yield(zero, fmt.Errorf("scan: %w", err))
+				return
+			}
+			if !yield(v(), nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, fmt.Errorf("rows: %w", err))
+		}
+	}
+}
+
+// cqlQuery builds a query and binds args from either a map or a struct
+func (o *DB) cqlQuery(ctx context.Context, stmt string, names []string, args interface{}) *gocqlx.Queryx {
+	if val, ok := args.(map[string]interface{}); ok {
+		return o.cql.ContextQuery(ctx, stmt, names).BindMap(val)
+	}
+	return o.cql.ContextQuery(ctx, stmt, names).BindStruct(args)
+}
+
+// cqlReadQuery is cqlQuery for a read, which can be speculated
+func (o *DB) cqlReadQuery(ctx context.Context, stmt string, names []string, args interface{}) *gocqlx.Queryx {
+	query := o.cqlQuery(ctx, stmt, names, args)
+	o.speculate(query.Query)
+	return query
+}
+
+// newScanDest returns a destination that can be passed to a scanner, and a func to get the scanned value.
+// Pointer types are allocated so that *T can be used the same as T.
+func newScanDest[T any]() (func() T, interface{}) {
+	var v T
+	if t := reflect.TypeOf(v); t != nil && t.Kind() == reflect.Ptr {
+		p := reflect.New(t.Elem())
+		v = p.Interface().(T)
+		return func() T { return v }, p.Interface()
+	}
+	return func() T { return v }, &v
+}
+
+var (
+	sqlScannerType   = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	cqlUnmarshalType = reflect.TypeOf((*gocql.Unmarshaler)(nil)).Elem()
+	cqlUDTType       = reflect.TypeOf((*gocql.UDTUnmarshaler)(nil)).Elem()
+	timeType         = reflect.TypeOf(time.Time{})
+)
+
+// isStructScannable reports if t should be scanned by column name instead of position
+func isStructScannable(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+	p := reflect.PointerTo(t)
+	return !p.Implements(sqlScannerType) && !p.Implements(cqlUnmarshalType) && !p.Implements(cqlUDTType)
+}
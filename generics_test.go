@@ -0,0 +1,74 @@
+package sql
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	test_users "github.com/joematpal/go-sql/v2/test/users"
+)
+
+func Test_isStructScannable(t *testing.T) {
+	tests := []struct {
+		name string
+		t    reflect.Type
+		want bool
+	}{
+		{
+			name: "should pass; struct",
+			t:    reflect.TypeOf(test_users.User{}),
+			want: true,
+		},
+		{
+			name: "should pass; pointer to struct",
+			t:    reflect.TypeOf(&test_users.User{}),
+			want: true,
+		},
+		{
+			name: "should pass; string",
+			t:    reflect.TypeOf(""),
+			want: false,
+		},
+		{
+			name: "should pass; time",
+			t:    reflect.TypeOf(time.Time{}),
+			want: false,
+		},
+		{
+			name: "should pass; sql scanner",
+			t:    reflect.TypeOf(sql.NullString{}),
+			want: false,
+		},
+		{
+			name: "should pass; cql udt",
+			t:    reflect.TypeOf(test_users.Scope{}),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isStructScannable(tt.t); got != tt.want {
+				t.Errorf("isStructScannable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_newScanDest(t *testing.T) {
+	get, dest := newScanDest[*test_users.User]()
+	u, ok := dest.(*test_users.User)
+	if !ok {
+		t.Fatalf("newScanDest() dest = %T, want *test_users.User", dest)
+	}
+	u.UserID = "1"
+	if got := get(); got == nil || got.UserID != "1" {
+		t.Errorf("newScanDest() = %v, want UserID 1", got)
+	}
+
+	getS, destS := newScanDest[string]()
+	*destS.(*string) = "1"
+	if got := getS(); got != "1" {
+		t.Errorf("newScanDest() = %v, want 1", got)
+	}
+}
@@ -0,0 +1,182 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// MigrationFunc is a migration written in go, ie. a backfill that can't be written as a statement
+type MigrationFunc func(ctx context.Context, db *DB) error
+
+type goMigration struct {
+	up   MigrationFunc
+	down MigrationFunc
+}
+
+// goMigrationMarker starts the body that the source returns for a go migration, the driver runs the
+// function instead of the body. The NUL byte keeps it from matching a migration file.
+const goMigrationMarker = "\x00go-sql migration "
+
+const goMigrationIdentifier = "go"
+
+var goMigrations = struct {
+	sync.Mutex
+	m map[uint]goMigration
+}{
+	m: map[uint]goMigration{},
+}
+
+// RegisterMigration adds a go migration that runs between the file migrations by its version
+// and is tracked in the same version table. down can be nil when the migration can't be reverted.
+// The migrations are registered for every DB, the function can check DBSource when the backends differ.
+// It panics if up is nil or the version is already registered, as it is meant to be called from init.
+func RegisterMigration(version uint, up, down MigrationFunc) {
+	goMigrations.Lock()
+	defer goMigrations.Unlock()
+	if up == nil {
+		panic("sql: RegisterMigration up is nil")
+	}
+	if _, ok := goMigrations.m[version]; ok {
+		panic(fmt.Sprintf("sql: RegisterMigration called twice for version %d", version))
+	}
+	goMigrations.m[version] = goMigration{up: up, down: down}
+}
+
+func registeredMigrations() map[uint]goMigration {
+	goMigrations.Lock()
+	defer goMigrations.Unlock()
+	out := make(map[uint]goMigration, len(goMigrations.m))
+	for v, m := range goMigrations.m {
+		out[v] = m
+	}
+	return out
+}
+
+// goMigrationSource adds the versions of the go migrations to the versions of the file source
+type goMigrationSource struct {
+	source.Driver
+	migrations map[uint]goMigration
+	versions   []uint
+}
+
+func newGoMigrationSource(files source.Driver, migrations map[uint]goMigration) (source.Driver, error) {
+	if len(migrations) == 0 {
+		return files, nil
+	}
+
+	s := &goMigrationSource{Driver: files, migrations: migrations}
+	v, err := files.First()
+	for err == nil {
+		if _, ok := migrations[v]; ok {
+			return nil, fmt.Errorf("version %d is both a file and a go migration", v)
+		}
+		s.versions = append(s.versions, v)
+		v, err = files.Next(v)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	for v := range migrations {
+		s.versions = append(s.versions, v)
+	}
+	sort.Slice(s.versions, func(i, j int) bool { return s.versions[i] < s.versions[j] })
+	return s, nil
+}
+
+func (s *goMigrationSource) First() (uint, error) {
+	if len(s.versions) == 0 {
+		return 0, &os.PathError{Op: "first", Path: "go migrations", Err: os.ErrNotExist}
+	}
+	return s.versions[0], nil
+}
+
+func (s *goMigrationSource) Prev(version uint) (uint, error) {
+	i := sort.Search(len(s.versions), func(i int) bool { return s.versions[i] >= version })
+	if i == 0 || i == len(s.versions) || s.versions[i] != version {
+		return 0, &os.PathError{Op: "prev " + strconv.FormatUint(uint64(version), 10), Path: "go migrations", Err: os.ErrNotExist}
+	}
+	return s.versions[i-1], nil
+}
+
+func (s *goMigrationSource) Next(version uint) (uint, error) {
+	i := sort.Search(len(s.versions), func(i int) bool { return s.versions[i] > version })
+	if i == len(s.versions) {
+		return 0, &os.PathError{Op: "next " + strconv.FormatUint(uint64(version), 10), Path: "go migrations", Err: os.ErrNotExist}
+	}
+	return s.versions[i], nil
+}
+
+func (s *goMigrationSource) ReadUp(version uint) (io.ReadCloser, string, error) {
+	if _, ok := s.migrations[version]; ok {
+		return goMigrationBody(version), goMigrationIdentifier, nil
+	}
+	return s.Driver.ReadUp(version)
+}
+
+func (s *goMigrationSource) ReadDown(version uint) (io.ReadCloser, string, error) {
+	if m, ok := s.migrations[version]; ok {
+		if m.down == nil {
+			return nil, "", &os.PathError{Op: "read down " + strconv.FormatUint(uint64(version), 10), Path: "go migrations", Err: os.ErrNotExist}
+		}
+		return goMigrationBody(version), goMigrationIdentifier, nil
+	}
+	return s.Driver.ReadDown(version)
+}
+
+func goMigrationBody(version uint) io.ReadCloser {
+	return io.NopCloser(strings.NewReader(goMigrationMarker + strconv.FormatUint(uint64(version), 10)))
+}
+
+// parseGoMigrationBody returns the version of a go migration body
+func parseGoMigrationBody(body []byte) (uint, bool) {
+	if !bytes.HasPrefix(body, []byte(goMigrationMarker)) {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(string(body[len(goMigrationMarker):]), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(v), true
+}
+
+// goMigrationDriver runs the function of a go migration body and passes the other bodies to the driver.
+// golang-migrate only reads the down body when reverting, so the version it sets tells up and down apart.
+type goMigrationDriver struct {
+	database.Driver
+	db         *DB
+	migrations map[uint]goMigration
+}
+
+func (d *goMigrationDriver) Run(migration io.Reader) error {
+	body, err := io.ReadAll(migration)
+	if err != nil {
+		return err
+	}
+	version, ok := parseGoMigrationBody(body)
+	if !ok {
+		return d.Driver.Run(bytes.NewReader(body))
+	}
+
+	m := d.migrations[version]
+	fn := m.up
+	// The version is set to the target before running, which is below the migration when reverting
+	if current, _, err := d.Driver.Version(); err == nil && current < int(version) {
+		fn = m.down
+	}
+	if err := fn(context.Background(), d.db); err != nil {
+		return fmt.Errorf("go migration %d: %w", version, err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package sql
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultMigrateSharedDir is the directory used by every DBSource without a directory of its own
+const DefaultMigrateSharedDir = "shared"
+
+// defaultMigrateDirs follows the layout of database/ in this repo
+var defaultMigrateDirs = map[DBSource]string{
+	DBSource_postgres: "psql",
+	DBSource_mysql:    "mysql",
+	DBSource_cql:      "cql",
+	DBSource_sqlite:   "sqlite",
+}
+
+// MigrateDir returns the directory the migrations are read from. With MigrateDialectDirs MigratePath is a root
+// and the directory of the DBSource is used when it exists, then the shared directory when that exists.
+// Directories can only be checked for MigrateFS and local paths, for other sources the DBSource directory is used.
+func (o *DB) MigrateDir() string {
+	if !o.MigrateDialectDirs {
+		return o.MigratePath
+	}
+
+	dir := o.MigrateDirs[o.DBSource]
+	if dir == "" {
+		dir = defaultMigrateDirs[o.DBSource]
+	}
+	shared := o.MigrateSharedDir
+	if shared == "" {
+		shared = DefaultMigrateSharedDir
+	}
+
+	dialectDir, sharedDir := o.joinMigratePath(dir), o.joinMigratePath(shared)
+	if o.isMigrateDir(dialectDir) || !o.isMigrateDir(sharedDir) {
+		return dialectDir
+	}
+	return sharedDir
+}
+
+func (o *DB) joinMigratePath(dir string) string {
+	switch {
+	case o.MigrateFS != nil:
+		return path.Join(o.MigratePath, dir)
+	case strings.Contains(o.MigratePath, "://"):
+		return strings.TrimSuffix(o.MigratePath, "/") + "/" + dir
+	}
+	return filepath.Join(o.MigratePath, dir)
+}
+
+func (o *DB) isMigrateDir(dir string) bool {
+	var (
+		info fs.FileInfo
+		err  error
+	)
+	switch {
+	case o.MigrateFS != nil:
+		info, err = fs.Stat(o.MigrateFS, dir)
+	case strings.HasPrefix(dir, "file://"):
+		info, err = os.Stat(strings.TrimPrefix(dir, "file://"))
+	case strings.Contains(dir, "://"):
+		return false
+	default:
+		info, err = os.Stat(dir)
+	}
+	return err == nil && info.IsDir()
+}
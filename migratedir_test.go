@@ -0,0 +1,94 @@
+package sql
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDB_MigrateDir(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"psql", "shared"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fsys := fstest.MapFS{
+		"migrations/cql/01_init.up.cql":      {},
+		"migrations/common/01_init.up.sql":   {},
+		"migrations/postgres/01_init.up.sql": {},
+	}
+
+	tests := []struct {
+		name string
+		db   *DB
+		want string
+	}{
+		{
+			name: "should pass; dialect dirs disabled",
+			db:   &DB{DBSource: DBSource_postgres, MigratePath: root},
+			want: root,
+		},
+		{
+			name: "should pass; default dialect dir",
+			db:   &DB{DBSource: DBSource_postgres, MigratePath: root, MigrateDialectDirs: true},
+			want: filepath.Join(root, "psql"),
+		},
+		{
+			name: "should pass; fallback to the shared dir",
+			db:   &DB{DBSource: DBSource_mysql, MigratePath: root, MigrateDialectDirs: true},
+			want: filepath.Join(root, "shared"),
+		},
+		{
+			name: "should pass; file url",
+			db:   &DB{DBSource: DBSource_postgres, MigratePath: "file://" + root, MigrateDialectDirs: true},
+			want: "file://" + root + "/psql",
+		},
+		{
+			name: "should pass; remote source uses the dialect dir",
+			db:   &DB{DBSource: DBSource_cql, MigratePath: "s3://bucket/migrations/", MigrateDialectDirs: true},
+			want: "s3://bucket/migrations/cql",
+		},
+		{
+			name: "should pass; fs dialect dir",
+			db:   &DB{DBSource: DBSource_cql, MigrateFS: fsys, MigratePath: "migrations", MigrateDialectDirs: true},
+			want: "migrations/cql",
+		},
+		{
+			name: "should pass; fs mapped dirs",
+			db: &DB{
+				DBSource:           DBSource_postgres,
+				MigrateFS:          fsys,
+				MigratePath:        "migrations",
+				MigrateDialectDirs: true,
+				MigrateDirs:        map[DBSource]string{DBSource_postgres: "postgres"},
+				MigrateSharedDir:   "common",
+			},
+			want: "migrations/postgres",
+		},
+		{
+			name: "should pass; fs custom shared dir",
+			db: &DB{
+				DBSource:           DBSource_sqlite,
+				MigrateFS:          fsys,
+				MigratePath:        "migrations",
+				MigrateDialectDirs: true,
+				MigrateSharedDir:   "common",
+			},
+			want: "migrations/common",
+		},
+		{
+			name: "should pass; no dialect or shared dir",
+			db:   &DB{DBSource: DBSource_sqlite, MigrateFS: fsys, MigratePath: "migrations", MigrateDialectDirs: true},
+			want: "migrations/sqlite",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.db.MigrateDir(); got != tt.want {
+				t.Errorf("DB.MigrateDir() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/cassandra"
+	"github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// ErrNilVersion is returned by Migrator.Version when no migration has been applied
+var ErrNilVersion = migrate.ErrNilVersion
+
+// Migrator runs the migrations of MigrateDir, from MigrateFS when it is set, and the registered go migrations
+// against the connection of a DB.
+// It must be closed, closing it does not close the DB.
+type Migrator struct {
+	db     *DB
+	m      *migrate.Migrate
+	source source.Driver
+}
+
+// Migrator returns a Migrator for the connection of the DB
+func (o *DB) Migrator() (*Migrator, error) {
+	driver, err := o.migrateDriver(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return o.newMigrator(driver)
+}
+
+// newMigrator reads the migrations from the source of the DB and runs them with the driver
+func (o *DB) newMigrator(driver database.Driver) (*Migrator, error) {
+	var (
+		err        error
+		src        source.Driver
+		sourceName string
+	)
+	if o.MigrateFS != nil {
+		sourceName = "iofs"
+		src, err = iofs.New(o.MigrateFS, o.MigrateDir())
+		if err != nil {
+			driver.Close()
+			return nil, fmt.Errorf("new fs: %w", err)
+		}
+	} else {
+		sourceName = o.GetMigratePath()
+		src, err = source.Open(sourceName)
+		if err != nil {
+			driver.Close()
+			return nil, fmt.Errorf("open source: %w", err)
+		}
+	}
+
+	lockTimeout := migrate.DefaultLockTimeout
+	if _, ok := driver.(*cqlLockDriver); ok {
+		// The lease gives up after MigrateLockTimeout, golang-migrate must not give up before it
+		lockTimeout = o.MigrateLockTimeout + o.MigrateLockTTL
+	}
+
+	migrations := registeredMigrations()
+	src, err = newGoMigrationSource(src, migrations)
+	if err != nil {
+		driver.Close()
+		return nil, fmt.Errorf("go migrations: %w", err)
+	}
+	if len(migrations) > 0 {
+		driver = &goMigrationDriver{Driver: driver, db: o, migrations: migrations}
+	}
+
+	m, err := migrate.NewWithInstance(sourceName, src, o.DBName, driver)
+	if err != nil {
+		src.Close()
+		driver.Close()
+		return nil, fmt.Errorf("migrations instance: %w", err)
+	}
+	m.Log = migrateLogger{o}
+	m.LockTimeout = lockTimeout
+
+	return &Migrator{db: o, m: m, source: src}, nil
+}
+
+// Up applies every pending migration.
+// With MigrateDryRun, Up, Down, Steps, Goto and Force write what they would do to MigrateDryRunOutput
+// and change nothing.
+func (m *Migrator) Up() error {
+	if m.db.MigrateDryRun {
+		return m.dryRun(m.Plan())
+	}
+	return m.noChange("up", m.m.Up())
+}
+
+// Down reverts every applied migration
+func (m *Migrator) Down() error {
+	if m.db.MigrateDryRun {
+		return m.dryRun(m.plan(func(current int, v uint) bool { return int(v) <= current }, true))
+	}
+	return m.noChange("down", m.m.Down())
+}
+
+// Steps applies n migrations, or reverts them when n is negative
+func (m *Migrator) Steps(n int) error {
+	if m.db.MigrateDryRun {
+		return m.dryRun(m.planSteps(n))
+	}
+	return m.noChange("steps", m.m.Steps(n))
+}
+
+// Goto migrates up or down to the version
+func (m *Migrator) Goto(version uint) error {
+	if m.db.MigrateDryRun {
+		return m.dryRun(m.planGoto(version))
+	}
+	return m.noChange("goto", m.m.Migrate(version))
+}
+
+// Force sets the version without running a migration and clears the dirty flag,
+// -1 means that no migration has been applied
+func (m *Migrator) Force(version int) error {
+	if m.db.MigrateDryRun {
+		_, err := fmt.Fprintf(m.db.migrateDryRunOutput(), "force version: %d\n", version)
+		return err
+	}
+	if err := m.m.Force(version); err != nil {
+		return fmt.Errorf("migrations force: %w", err)
+	}
+	return nil
+}
+
+// Version returns the current version and if the last migration failed half way,
+// ErrNilVersion is returned when no migration has been applied
+func (m *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, ErrNilVersion
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("migrations version: %w", err)
+	}
+	return version, dirty, nil
+}
+
+// Pending returns the versions of the source that are after the current version
+func (m *Migrator) Pending() ([]uint, error) {
+	current, _, err := m.Version()
+	if err != nil && !errors.Is(err, ErrNilVersion) {
+		return nil, err
+	}
+	applied := err == nil
+
+	versions, err := m.versions()
+	if err != nil {
+		return nil, err
+	}
+
+	out := []uint{}
+	for _, v := range versions {
+		if !applied || v > current {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+// versions lists every version of the source in order
+func (m *Migrator) versions() ([]uint, error) {
+	out := []uint{}
+	v, err := m.source.First()
+	for err == nil {
+		out = append(out, v)
+		v, err = m.source.Next(v)
+	}
+	if !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("migrations source: %w", err)
+	}
+	return out, nil
+}
+
+// Close releases the source and the connection held by the migrator
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+	if srcErr != nil {
+		return fmt.Errorf("close source: %w", srcErr)
+	}
+	if dbErr != nil {
+		return fmt.Errorf("close database: %w", dbErr)
+	}
+	return nil
+}
+
+func (o *DB) migrateDryRunOutput() io.Writer {
+	if o.MigrateDryRunOutput == nil {
+		return os.Stdout
+	}
+	return o.MigrateDryRunOutput
+}
+
+// dryRun writes the plan with the contents of the migrations instead of running it
+func (m *Migrator) dryRun(plan Plan, err error) error {
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(m.db.migrateDryRunOutput(), plan.format(true))
+	return err
+}
+
+func (m *Migrator) noChange(op string, err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		m.db.Debugf("migrate %s: %v", op, err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("migrations %s: %w", op, err)
+	}
+	m.db.Debugf("migrate %s: success", op)
+	return nil
+}
+
+// migrateDriver returns the golang-migrate driver for the connection.
+// Closing the driver must not close the DB so postgres and mysql get a connection of their own,
+// and sqlite and cql get a driver that does not close the shared connection.
+// cql also gets a lease lock as the cassandra driver only locks within the process.
+func (o *DB) migrateDriver(ctx context.Context) (database.Driver, error) {
+	switch o.DBSource {
+	case DBSource_sqlite:
+		if o.sql == nil {
+			return nil, ErrNoSourceConfigured
+		}
+		driver, err := sqlite.WithInstance(o.sql.DB, &sqlite.Config{
+			DatabaseName: o.DBName,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("sqlite instance: %w", err)
+		}
+		return sharedDriver{driver}, nil
+	case DBSource_postgres:
+		if o.sql == nil {
+			return nil, ErrNoSourceConfigured
+		}
+		conn, err := o.sql.DB.Conn(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("postgres conn: %w", err)
+		}
+		driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{
+			DatabaseName: o.DBName,
+		})
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("postgres instance: %w", err)
+		}
+		return driver, nil
+	case DBSource_mysql:
+		if o.sql == nil {
+			return nil, ErrNoSourceConfigured
+		}
+		conn, err := o.sql.DB.Conn(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("mysql conn: %w", err)
+		}
+		driver, err := mysql.WithConnection(ctx, conn, &mysql.Config{
+			DatabaseName: o.DBName,
+		})
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("mysql instance: %w", err)
+		}
+		return driver, nil
+	case DBSource_cql:
+		if o.cql == nil {
+			return nil, ErrNoSourceConfigured
+		}
+		driver, err := cassandra.WithInstance(o.cql.Session, &cassandra.Config{
+			// CQL connection currently does not support query string arguments
+			// Manually override the multi statments flag
+			MultiStatementEnabled: true,
+			KeyspaceName:          o.DBName,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cql instance: %w", err)
+		}
+		return o.newCQLLockDriver(driver)
+	}
+	return nil, errors.New("db driver not supported")
+}
+
+// sharedDriver is a driver over the connection of the DB, which is closed by the DB and not by the migrator
+type sharedDriver struct {
+	database.Driver
+}
+
+func (sharedDriver) Close() error {
+	return nil
+}
+
+// migrateLogger logs the golang-migrate output with the Debugger of the DB
+type migrateLogger struct {
+	db *DB
+}
+
+func (l migrateLogger) Printf(format string, v ...interface{}) {
+	l.db.Debugf(format, v...)
+}
+
+func (l migrateLogger) Verbose() bool {
+	return false
+}
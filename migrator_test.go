@@ -0,0 +1,197 @@
+package sql
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang-migrate/migrate/v4/database/stub"
+	"github.com/google/go-cmp/cmp"
+)
+
+func newTestMigrator(t *testing.T) *Migrator {
+	t.Helper()
+	fsys := fstest.MapFS{
+		"migrations/01_init.up.sql":    {Data: []byte("CREATE TABLE a (id int)")},
+		"migrations/01_init.down.sql":  {Data: []byte("DROP TABLE a")},
+		"migrations/02_users.up.sql":   {Data: []byte("CREATE TABLE b (id int)")},
+		"migrations/02_users.down.sql": {Data: []byte("DROP TABLE b")},
+		"migrations/05_groups.up.sql":  {Data: []byte("CREATE TABLE c (id int)")},
+	}
+	db, err := New(WithDBSource("sqlite"), WithMigrateFS(fsys), WithMigratePath("migrations"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	driver, err := stub.WithInstance(nil, &stub.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := db.newMigrator(driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestMigrator(t *testing.T) {
+	m := newTestMigrator(t)
+
+	if _, _, err := m.Version(); err != ErrNilVersion {
+		t.Fatalf("Migrator.Version() error = %v, want %v", err, ErrNilVersion)
+	}
+	pending, err := m.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]uint{1, 2, 5}, pending); diff != "" {
+		t.Errorf("Migrator.Pending() mismatch (-want +got):\n%s", diff)
+	}
+
+	steps := []struct {
+		name        string
+		run         func() error
+		wantVersion uint
+		wantPending []uint
+	}{
+		{name: "steps", run: func() error { return m.Steps(1) }, wantVersion: 1, wantPending: []uint{2, 5}},
+		{name: "up", run: m.Up, wantVersion: 5, wantPending: []uint{}},
+		{name: "up without changes", run: m.Up, wantVersion: 5, wantPending: []uint{}},
+		{name: "goto", run: func() error { return m.Goto(2) }, wantVersion: 2, wantPending: []uint{5}},
+		{name: "force", run: func() error { return m.Force(1) }, wantVersion: 1, wantPending: []uint{2, 5}},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: error = %v", step.name, err)
+		}
+		version, dirty, err := m.Version()
+		if err != nil {
+			t.Fatalf("%s: Migrator.Version() error = %v", step.name, err)
+		}
+		if version != step.wantVersion || dirty {
+			t.Errorf("%s: Migrator.Version() = %v, %v, want %v, false", step.name, version, dirty, step.wantVersion)
+		}
+		pending, err := m.Pending()
+		if err != nil {
+			t.Fatalf("%s: Migrator.Pending() error = %v", step.name, err)
+		}
+		if diff := cmp.Diff(step.wantPending, pending); diff != "" {
+			t.Errorf("%s: Migrator.Pending() mismatch (-want +got):\n%s", step.name, diff)
+		}
+	}
+
+	if err := m.Down(); err != nil {
+		t.Fatalf("Migrator.Down() error = %v", err)
+	}
+	if _, _, err := m.Version(); err != ErrNilVersion {
+		t.Errorf("Migrator.Version() error = %v, want %v", err, ErrNilVersion)
+	}
+}
+
+func TestMigrator_dryRun(t *testing.T) {
+	m := newTestMigrator(t)
+	if err := m.Steps(2); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	m.db.MigrateDryRun = true
+	m.db.MigrateDryRunOutput = &out
+
+	tests := []struct {
+		name string
+		run  func() error
+		want string
+	}{
+		{name: "up", run: m.Up, want: "version: 2\n5 groups sha256:e6cfd3533d02c8424b1fa86e243cb802a9a4c232b93dad8a49892d41396fbf5e\n    CREATE TABLE c (id int)\n"},
+		{name: "steps up", run: func() error { return m.Steps(3) }, want: "version: 2\n5 groups sha256:e6cfd3533d02c8424b1fa86e243cb802a9a4c232b93dad8a49892d41396fbf5e\n    CREATE TABLE c (id int)\n"},
+		{name: "steps down", run: func() error { return m.Steps(-1) }, want: "version: 2\ndown 2 users sha256:c5cae5280532fda0cf7a27f53e9081a05bc1ddaa2bee33a2092efcb0875946d8\n    DROP TABLE b\n"},
+		{name: "down", run: m.Down, want: "version: 2\ndown 2 users sha256:c5cae5280532fda0cf7a27f53e9081a05bc1ddaa2bee33a2092efcb0875946d8\n    DROP TABLE b\ndown 1 init sha256:1eb7532812c5a99b23c100f2a77aac27c395d097af31d305ff20b01fdc7e272b\n    DROP TABLE a\n"},
+		{name: "goto", run: func() error { return m.Goto(1) }, want: "version: 2\ndown 2 users sha256:c5cae5280532fda0cf7a27f53e9081a05bc1ddaa2bee33a2092efcb0875946d8\n    DROP TABLE b\n"},
+		{name: "force", run: func() error { return m.Force(5) }, want: "force version: 5\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out.Reset()
+			if err := tt.run(); err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, out.String()); diff != "" {
+				t.Errorf("dry run output mismatch (-want +got):\n%s", diff)
+			}
+			version, dirty, err := m.Version()
+			if err != nil || version != 2 || dirty {
+				t.Errorf("Migrator.Version() = %v, %v, %v, want 2, false, <nil>", version, dirty, err)
+			}
+		})
+	}
+
+	if err := m.Goto(4); err == nil {
+		t.Error("Migrator.Goto() dry run of a missing version, want an error")
+	}
+}
+
+func TestMigrator_goMigrations(t *testing.T) {
+	var calls []string
+	goMigrations.Lock()
+	goMigrations.m[3] = goMigration{
+		up: func(ctx context.Context, db *DB) error {
+			calls = append(calls, "up 3")
+			return nil
+		},
+		down: func(ctx context.Context, db *DB) error {
+			calls = append(calls, "down 3")
+			return nil
+		},
+	}
+	goMigrations.m[4] = goMigration{
+		up: func(ctx context.Context, db *DB) error {
+			calls = append(calls, "up 4")
+			return nil
+		},
+	}
+	goMigrations.Unlock()
+	t.Cleanup(func() {
+		goMigrations.Lock()
+		delete(goMigrations.m, 3)
+		delete(goMigrations.m, 4)
+		goMigrations.Unlock()
+	})
+
+	m := newTestMigrator(t)
+	pending, err := m.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]uint{1, 2, 3, 4, 5}, pending); diff != "" {
+		t.Errorf("Migrator.Pending() mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("Migrator.Up() error = %v", err)
+	}
+	if err := m.Down(); err != nil {
+		t.Fatalf("Migrator.Down() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"up 3", "up 4", "down 3"}, calls); diff != "" {
+		t.Errorf("go migrations mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRegisterMigration(t *testing.T) {
+	noop := func(ctx context.Context, db *DB) error { return nil }
+	RegisterMigration(1000, noop, nil)
+	t.Cleanup(func() {
+		goMigrations.Lock()
+		delete(goMigrations.m, 1000)
+		goMigrations.Unlock()
+	})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterMigration() twice did not panic")
+		}
+	}()
+	RegisterMigration(1000, noop, nil)
+}
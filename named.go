@@ -0,0 +1,133 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CompileNamed converts a statement with :name parameters to the placeholders of dbSource
+// and returns the names in the order of the placeholders.
+// Postgres gets $n with the same n for a repeated name, the other sources get ? with the name repeated.
+// Casts (::) and names inside of quotes and comments are left as is.
+func CompileNamed(dbSource DBSource, stmt string) (string, []string, error) {
+	var (
+		sb      strings.Builder
+		names   []string
+		indexes = map[string]int{}
+		last    int
+	)
+
+	err := lexStatement(dbSource, stmt, func(i int) int {
+		if stmt[i] != ':' {
+			return i + 1
+		}
+		// :: casts and := assignments
+		if next := peek(stmt, i+1); next == ':' || next == '=' {
+			return i + 2
+		}
+		if !isNameStart(peek(stmt, i+1)) {
+			return i + 1
+		}
+
+		end := i + 1
+		for end < len(stmt) && isNameByte(stmt[end]) {
+			end++
+		}
+		name := stmt[i+1 : end]
+
+		sb.WriteString(stmt[last:i])
+		if dbSource == DBSource_postgres {
+			n, ok := indexes[name]
+			if !ok {
+				names = append(names, name)
+				n = len(names)
+				indexes[name] = n
+			}
+			sb.WriteString("$" + strconv.Itoa(n))
+		} else {
+			names = append(names, name)
+			sb.WriteString("?")
+		}
+		last = end
+		return end
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	sb.WriteString(stmt[last:])
+
+	return sb.String(), names, nil
+}
+
+func isNameStart(b byte) bool {
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func isNameByte(b byte) bool {
+	return isNameStart(b) || isDigit(b)
+}
+
+// SelectNamed is Select for a statement with :name parameters, args is a struct or a map[string]interface{}
+func (o *DB) SelectNamed(ctx context.Context, dst interface{}, stmt string, args interface{}) error {
+	stmt, names, err := CompileNamed(o.DBSource, stmt)
+	if err != nil {
+		return fmt.Errorf("compile named: %w", err)
+	}
+	return o.SelectContext(ctx, dst, stmt, names, args)
+}
+
+// GetNamed is Get for a statement with :name parameters, args is a struct or a map[string]interface{}
+func (o *DB) GetNamed(ctx context.Context, dst interface{}, stmt string, args interface{}) error {
+	stmt, names, err := CompileNamed(o.DBSource, stmt)
+	if err != nil {
+		return fmt.Errorf("compile named: %w", err)
+	}
+	return o.GetContext(ctx, dst, stmt, names, args)
+}
+
+// ExecNamed is Exec for a statement with :name parameters, args is a struct or a map[string]interface{}
+func (o *DB) ExecNamed(ctx context.Context, stmt string, args interface{}) error {
+	stmt, names, err := CompileNamed(o.DBSource, stmt)
+	if err != nil {
+		return fmt.Errorf("compile named: %w", err)
+	}
+	return o.ExecContext(ctx, stmt, names, args)
+}
+
+// QueryNamed runs a statement with :name parameters, the returned Rows must be closed
+func (o *DB) QueryNamed(ctx context.Context, stmt string, args interface{}) (Rows, error) {
+	stmt, names, err := CompileNamed(o.DBSource, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("compile named: %w", err)
+	}
+	return o.bindQuery(ctx, stmt, names, args)
+}
+
+// SelectNamed is Select for a statement with :name parameters, args is a struct or a map[string]interface{}
+func (tx *Tx) SelectNamed(ctx context.Context, dst interface{}, stmt string, args interface{}) error {
+	stmt, names, err := CompileNamed(tx.db.DBSource, stmt)
+	if err != nil {
+		return fmt.Errorf("compile named: %w", err)
+	}
+	return tx.SelectContext(ctx, dst, stmt, names, args)
+}
+
+// GetNamed is Get for a statement with :name parameters, args is a struct or a map[string]interface{}
+func (tx *Tx) GetNamed(ctx context.Context, dst interface{}, stmt string, args interface{}) error {
+	stmt, names, err := CompileNamed(tx.db.DBSource, stmt)
+	if err != nil {
+		return fmt.Errorf("compile named: %w", err)
+	}
+	return tx.GetContext(ctx, dst, stmt, names, args)
+}
+
+// ExecNamed is Exec for a statement with :name parameters, args is a struct or a map[string]interface{}
+func (tx *Tx) ExecNamed(ctx context.Context, stmt string, args interface{}) error {
+	stmt, names, err := CompileNamed(tx.db.DBSource, stmt)
+	if err != nil {
+		return fmt.Errorf("compile named: %w", err)
+	}
+	return tx.ExecContext(ctx, stmt, names, args)
+}
@@ -0,0 +1,74 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompileNamed(t *testing.T) {
+	tests := []struct {
+		name      string
+		dbSource  DBSource
+		stmt      string
+		want      string
+		wantNames []string
+		wantErr   bool
+	}{
+		{
+			name:      "should pass; postgres reuses the placeholder for a repeated name",
+			dbSource:  DBSource_postgres,
+			stmt:      "SELECT * FROM users WHERE user_id = :user_id OR created_by = :user_id AND email = :email",
+			want:      "SELECT * FROM users WHERE user_id = $1 OR created_by = $1 AND email = $2",
+			wantNames: []string{"user_id", "email"},
+		},
+		{
+			name:      "should pass; postgres casts, strings and comments",
+			dbSource:  DBSource_postgres,
+			stmt:      "SELECT ':skip', $$:skip$$ FROM users /* :skip */ WHERE user_id = :user_id::text -- :skip",
+			want:      "SELECT ':skip', $$:skip$$ FROM users /* :skip */ WHERE user_id = $1::text -- :skip",
+			wantNames: []string{"user_id"},
+		},
+		{
+			name:      "should pass; mysql repeats the name",
+			dbSource:  DBSource_mysql,
+			stmt:      "UPDATE users SET username = :username, updated_by = :user_id WHERE user_id = :user_id AND @x := 1",
+			want:      "UPDATE users SET username = ?, updated_by = ? WHERE user_id = ? AND @x := 1",
+			wantNames: []string{"username", "user_id", "user_id"},
+		},
+		{
+			name:      "should pass; sqlite",
+			dbSource:  DBSource_sqlite,
+			stmt:      "SELECT * FROM users WHERE user_id=:user_id",
+			want:      "SELECT * FROM users WHERE user_id=?",
+			wantNames: []string{"user_id"},
+		},
+		{
+			name:      "should pass; cql map literal",
+			dbSource:  DBSource_cql,
+			stmt:      "UPDATE user_settings SET metadata = {'a': 'b'} WHERE user_id = :user_id",
+			want:      "UPDATE user_settings SET metadata = {'a': 'b'} WHERE user_id = ?",
+			wantNames: []string{"user_id"},
+		},
+		{
+			name:     "should fail; unterminated quote",
+			dbSource: DBSource_cql,
+			stmt:     "SELECT * FROM users WHERE user_id = ':user_id",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotNames, err := CompileNamed(tt.dbSource, tt.stmt)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CompileNamed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CompileNamed() = %v, want %v", got, tt.want)
+			}
+			if !cmp.Equal(gotNames, tt.wantNames) {
+				t.Error(cmp.Diff(gotNames, tt.wantNames))
+			}
+		})
+	}
+}
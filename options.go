@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"net/url"
 	"strings"
@@ -69,6 +70,13 @@ func (o *DB) applyOption(out *DB) error {
 		out.ConnectTimeout = o.ConnectTimeout
 	}
 
+	out.Pool.merge(o.Pool)
+	out.CQLPolicy.merge(o.CQLPolicy)
+
+	if o.TLS.Mode != "" {
+		out.TLS = o.TLS
+	}
+
 	return nil
 }
 
@@ -77,13 +85,13 @@ func (o *DB) IsValid() error {
 	switch o.DBSource {
 	case DBSource_postgres, DBSource_mysql, DBSource_sqlite:
 		// Check if there is already and existing connection
-		if err := dbs.GetSQLConnection(o); err != nil {
+		if err := o.connect(openSQL); err != nil {
 			return fmt.Errorf("sql conn: %v", err)
 		}
 		return nil
 	case DBSource_cql:
 		// Check if there is already and existing connection
-		if err := dbs.GetCQLConnection(o); err != nil {
+		if err := o.connect(openCQL); err != nil {
 			return fmt.Errorf("cql conn: %v", err)
 		}
 		return nil
@@ -92,12 +100,30 @@ func (o *DB) IsValid() error {
 	return fmt.Errorf("db source %s is not supported", o.DBSource)
 }
 
+// connect opens the connection of the DB or shares the one of the registry. The mapper is set on the
+// connection and funcs can't be compared, so a DB with its own map funcs opens its own connection.
+func (o *DB) connect(open func(*DB) error) error {
+	if o.ownMapper {
+		o.Debugf("connection: own map funcs, not shared")
+		return open(o)
+	}
+	return o.getRegistry().acquire(o, open)
+}
+
+func (o *DB) getRegistry() *Registry {
+	if o.registry != nil {
+		return o.registry
+	}
+	return defaultRegistry
+}
+
 // GetMigratePath will add the protocol if it is not there assuming that the path is a local file
 func (o *DB) GetMigratePath() string {
-	if strings.Contains(o.MigratePath, "://") {
-		return o.MigratePath
+	migratePath := o.MigrateDir()
+	if strings.Contains(migratePath, "://") {
+		return migratePath
 	}
-	return fmt.Sprintf("file://%s", o.MigratePath)
+	return fmt.Sprintf("file://%s", migratePath)
 }
 
 func (o *DB) Debugf(format string, args ...interface{}) {
@@ -177,6 +203,15 @@ func WithMigrate(migrate bool) Option {
 	})
 }
 
+// WithRegistry shares the connection with the DBs of the same config in r instead of the default registry,
+// ie. to keep the connections of a test or a tenant apart
+func WithRegistry(r *Registry) Option {
+	return optionApplyFunc(func(o *DB) error {
+		o.registry = r
+		return nil
+	})
+}
+
 // WithMigratePath pass in the filepath the db needs for the migrations that need to be added
 func WithMigratePath(migratePath string) Option {
 	return optionApplyFunc(func(o *DB) error {
@@ -290,6 +325,156 @@ func WithConnectTimeout(connectTimeout time.Duration) Option {
 	})
 }
 
+// WithTLS encrypts the connection, the mode defaults to verify-full.
+// For mysql the config is registered with the driver and for postgres the connection is opened with pgx.
+func WithTLS(tlsConfig TLSConfig) Option {
+	return optionApplyFunc(func(d *DB) error {
+		if tlsConfig.Mode == "" {
+			tlsConfig.Mode = TLSModeVerifyFull
+		}
+		if err := tlsConfig.validate(); err != nil {
+			return err
+		}
+		d.TLS = tlsConfig
+		return nil
+	})
+}
+
+// WithPool sets the fields of the pool config that are not zero, the others keep their defaults
+func WithPool(pool PoolConfig) Option {
+	return optionApplyFunc(func(d *DB) error {
+		d.Pool.merge(pool)
+		return nil
+	})
+}
+
+// WithCQLHostSelection sets the order the cql hosts are tried in, ie. the replicas of the partition in the local
+// rack and datacenter first.
+func WithCQLHostSelection(selection CQLHostSelection) Option {
+	return optionApplyFunc(func(d *DB) error {
+		if err := selection.validate(); err != nil {
+			return err
+		}
+		d.CQLPolicy.HostSelection = selection
+		return nil
+	})
+}
+
+// WithCQLRetryPolicy retries the failed cql queries with an exponential backoff, and with a lower consistency
+// when DowngradeConsistency is set. gocql does not retry by default.
+func WithCQLRetryPolicy(retry CQLRetryPolicy) Option {
+	return optionApplyFunc(func(d *DB) error {
+		if err := retry.validate(); err != nil {
+			return err
+		}
+		d.CQLPolicy.Retry = retry
+		return nil
+	})
+}
+
+// WithCQLSpeculativeExecution sends a cql read to up to attempts more hosts, one every delay until a host answers.
+// Only the reads are marked as idempotent, the writes are never sent more than once.
+func WithCQLSpeculativeExecution(attempts int, delay time.Duration) Option {
+	return optionApplyFunc(func(d *DB) error {
+		speculative := CQLSpeculativeExecution{Attempts: attempts, Delay: delay}
+		if err := speculative.validate(); err != nil {
+			return err
+		}
+		d.CQLPolicy.SpeculativeExecution = speculative
+		return nil
+	})
+}
+
+// WithCQLReconnectionPolicy sets how a cql host is reconnected to before it is marked down,
+// gocql retries 3 times every second by default
+func WithCQLReconnectionPolicy(reconnection CQLReconnectionPolicy) Option {
+	return optionApplyFunc(func(d *DB) error {
+		if err := reconnection.validate(); err != nil {
+			return err
+		}
+		d.CQLPolicy.Reconnection = reconnection
+		return nil
+	})
+}
+
+// WithTxRetries sets how many times RunInTx retries a transaction that failed on a serialization failure
+func WithTxRetries(retries int) Option {
+	return optionApplyFunc(func(d *DB) error {
+		if retries < 0 {
+			return errors.New("tx retries cannot be negative")
+		}
+		d.TxRetries = retries
+		return nil
+	})
+}
+
+// WithPageTokenKey sets the key used to encrypt the page tokens returned by SelectPage, it is required by SelectPage
+func WithPageTokenKey(key []byte) Option {
+	return optionApplyFunc(func(d *DB) error {
+		if len(key) == 0 {
+			return errors.New("page token key cannot be empty")
+		}
+		d.PageTokenKey = key
+		return nil
+	})
+}
+
+// WithStmtCacheSize sets how many prepared statements are cached per connection, 0 disables the cache
+func WithStmtCacheSize(size int) Option {
+	return optionApplyFunc(func(d *DB) error {
+		if size < 0 {
+			return errors.New("stmt cache size cannot be negative")
+		}
+		d.StmtCacheSize = size
+		return nil
+	})
+}
+
+// WithMigrateDialectDirs treats MigratePath as a root and reads the migrations from the directory of the DBSource,
+// ie. database/psql for postgres, or from the shared directory when it has none.
+// dirs overrides the directory of a DBSource and an empty sharedDir is DefaultMigrateSharedDir.
+func WithMigrateDialectDirs(dirs map[DBSource]string, sharedDir string) Option {
+	return optionApplyFunc(func(d *DB) error {
+		d.MigrateDialectDirs = true
+		d.MigrateDirs = dirs
+		d.MigrateSharedDir = sharedDir
+		return nil
+	})
+}
+
+// WithMigrateDryRun writes the migrations that would run with their checksums and contents instead of running them,
+// for the connect time migrations as well as every Migrator method that changes the version
+func WithMigrateDryRun(dryRun bool) Option {
+	return optionApplyFunc(func(d *DB) error {
+		d.MigrateDryRun = dryRun
+		return nil
+	})
+}
+
+// WithMigrateDryRunOutput sets where the dry run plans are written, it defaults to os.Stdout
+func WithMigrateDryRunOutput(w io.Writer) Option {
+	return optionApplyFunc(func(d *DB) error {
+		d.MigrateDryRunOutput = w
+		return nil
+	})
+}
+
+// WithMigrateLock sets the ttl of the cql migrations lock and how long to wait for it,
+// the lock is renewed while the migrations run so the ttl only matters when an instance dies
+func WithMigrateLock(ttl, timeout time.Duration) Option {
+	return optionApplyFunc(func(d *DB) error {
+		if ttl < time.Second {
+			return errors.New("migrate lock ttl must be at least a second")
+		}
+		if timeout <= 0 {
+			return errors.New("migrate lock timeout must be greater than 0")
+		}
+		d.MigrateLockTTL = ttl
+		d.MigrateLockTimeout = timeout
+		return nil
+	})
+}
+
 func WithRawQuery(rawQuery string) Option {
 	return optionApplyFunc(func(d *DB) error {
 		d.RawQuery = rawQuery
@@ -318,6 +503,7 @@ func (o *DB) getDataSource() (string, error) {
 func WithMapFunc(mapFunc func(string) string) Option {
 	return optionApplyFunc(func(d *DB) error {
 		d.mapFunc = mapFunc
+		d.ownMapper = true
 		return nil
 	})
 }
@@ -325,6 +511,7 @@ func WithMapFunc(mapFunc func(string) string) Option {
 func WithTagMapFunc(tagMapFunc func(string) string) Option {
 	return optionApplyFunc(func(d *DB) error {
 		d.tagMapFunc = tagMapFunc
+		d.ownMapper = true
 		return nil
 	})
 }
@@ -368,7 +555,13 @@ func (o *DB) getMysqlDataSource() (string, error) {
 		return sb.String(), err
 	}
 
-	// TODO: Add in the TLS support
+	// The config is registered with the driver under this name before the connection is opened
+	if o.TLS.Enabled() && !strings.Contains(o.RawQuery, "tls=") {
+		if o.RawQuery != "" {
+			sb.WriteString("&")
+		}
+		sb.WriteString("tls=" + o.TLS.mysqlConfigName())
+	}
 	return sb.String(), nil
 }
 
@@ -412,9 +605,12 @@ func (o *DB) getPostgresDataSource() (string, error) {
 		return sb.String(), err
 	}
 
-	// TODO: Add in the TLS support
+	sslmode := "sslmode=disable"
+	if o.TLS.Enabled() {
+		sslmode = o.TLS.postgresParams()
+	}
 	if !strings.Contains(o.RawQuery, "sslmode") {
-		if _, err := sb.WriteString(fmt.Sprintf("sslmode=%s", "disable")); err != nil {
+		if _, err := sb.WriteString(sslmode); err != nil {
 			return sb.String(), err
 		}
 	}
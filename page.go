@@ -0,0 +1,312 @@
+package sql
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/joematpal/go-sql/v2/table"
+)
+
+var (
+	ErrInvalidPageToken = errors.New("invalid page token")
+	ErrNoPageTokenKey   = errors.New("no page token key, see WithPageTokenKey")
+)
+
+// PageRequest is the page that SelectPage should return
+type PageRequest struct {
+	// Size is the max number of rows in the page
+	Size int
+	// Token is the NextToken from the previous page, empty for the first page
+	Token string
+	// Table is used by the sql sources for its primary key, the key columns must be selected by the statement.
+	// Not needed for cql as the driver keeps track of the page state.
+	Table table.Table
+}
+
+// pageToken is the payload of a page token before it is encrypted
+type pageToken struct {
+	// State is the cql page state
+	State []byte `json:"s,omitempty"`
+	// Keys are the last seen primary key values for sql
+	Keys pageKeys `json:"k,omitempty"`
+}
+
+// pageKeys keep the type of each key, ie. a time.Time key is not decoded as a string
+type pageKeys []interface{}
+
+// pageKey is a scalar key with its type
+type pageKey struct {
+	Type  string          `json:"t"`
+	Value json.RawMessage `json:"v"`
+}
+
+func (keys pageKeys) MarshalJSON() ([]byte, error) {
+	out := make([]pageKey, len(keys))
+	for i, key := range keys {
+		typ, v, err := pageKeyValue(key)
+		if err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = pageKey{Type: typ, Value: b}
+	}
+	return json.Marshal(out)
+}
+
+func (keys *pageKeys) UnmarshalJSON(b []byte) error {
+	var in []pageKey
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+	out := make(pageKeys, len(in))
+	for i, key := range in {
+		var err error
+		switch key.Type {
+		case "int":
+			var v int64
+			err, out[i] = json.Unmarshal(key.Value, &v), v
+		case "uint":
+			var v uint64
+			err, out[i] = json.Unmarshal(key.Value, &v), v
+		case "float":
+			var v float64
+			err, out[i] = json.Unmarshal(key.Value, &v), v
+		case "bool":
+			var v bool
+			err, out[i] = json.Unmarshal(key.Value, &v), v
+		case "string":
+			var v string
+			err, out[i] = json.Unmarshal(key.Value, &v), v
+		case "bytes":
+			var v []byte
+			err, out[i] = json.Unmarshal(key.Value, &v), v
+		case "time":
+			var v time.Time
+			err, out[i] = json.Unmarshal(key.Value, &v), v
+		default:
+			err = fmt.Errorf("unknown key type %q", key.Type)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	*keys = out
+	return nil
+}
+
+// pageKeyValue returns the type and the value of a scalar key, a driver.Valuer is encoded as its value
+func pageKeyValue(key interface{}) (string, interface{}, error) {
+	if valuer, ok := key.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "", nil, fmt.Errorf("page key: %w", err)
+		}
+		key = v
+	}
+	switch v := key.(type) {
+	case time.Time:
+		return "time", v, nil
+	case []byte:
+		return "bytes", v, nil
+	}
+
+	rv := reflect.ValueOf(key)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "int", rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "uint", rv.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return "float", rv.Float(), nil
+	case reflect.Bool:
+		return "bool", rv.Bool(), nil
+	case reflect.String:
+		return "string", rv.String(), nil
+	case reflect.Struct:
+		if t, ok := rv.Interface().(time.Time); ok {
+			return "time", t, nil
+		}
+	}
+	return "", nil, fmt.Errorf("page key of type %T is not a scalar", key)
+}
+
+// SelectPage selects one page of rows into dst and returns the token for the next page.
+// An empty token means that there are no more pages.
+//
+// For sql the statement is wrapped in a subquery that is ordered and filtered by the primary key of page.Table,
+// so it should not have its own ORDER BY or LIMIT.
+func (o *DB) SelectPage(ctx context.Context, dst interface{}, stmt string, names []string, args interface{}, page PageRequest) (string, error) {
+	if page.Size <= 0 {
+		return "", errors.New("page size must be greater than 0")
+	}
+	if len(o.PageTokenKey) == 0 {
+		return "", ErrNoPageTokenKey
+	}
+
+	var token pageToken
+	if page.Token != "" {
+		if err := o.decodePageToken(page.Token, stmt, &token); err != nil {
+			return "", err
+		}
+	}
+
+	if o.cql != nil {
+		query := o.cqlReadQuery(ctx, stmt, names, args).PageSize(page.Size).PageState(token.State)
+		defer query.Release()
+
+		iter := query.Iter()
+		state := iter.PageState()
+		if err := iter.Select(dst); err != nil {
+			return "", err
+		}
+		if len(state) == 0 {
+			return "", nil
+		}
+		return o.encodePageToken(stmt, pageToken{State: state})
+	}
+
+	if o.sql != nil {
+		return o.selectSQLPage(ctx, dst, stmt, names, args, page, token.Keys)
+	}
+
+	return "", ErrNoSourceConfigured
+}
+
+func (o *DB) selectSQLPage(ctx context.Context, dst interface{}, stmt string, names []string, args interface{}, page PageRequest, after []interface{}) (string, error) {
+	keys := page.Table.PrimaryKey()
+	if len(keys) == 0 {
+		return "", fmt.Errorf("table %q has no primary key to page by", page.Table.Name)
+	}
+	if len(after) != 0 && len(after) != len(keys) {
+		return "", ErrInvalidPageToken
+	}
+
+	namedStmt, err := CompileNamedStatement(o.DBSource, stmt, names)
+	if err != nil {
+		return "", fmt.Errorf("named statement: %w", err)
+	}
+	query, queryArgs, err := o.sql.BindNamed(namedStmt, args)
+	if err != nil {
+		return "", fmt.Errorf("bind named: %w", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT * FROM (")
+	sb.WriteString(query)
+	sb.WriteString(") AS page")
+	if len(after) != 0 {
+		placeholders := make([]string, len(keys))
+		for i := range keys {
+			queryArgs = append(queryArgs, after[i])
+			placeholders[i] = "?"
+			if sqlx.BindType(o.sql.DriverName()) == sqlx.DOLLAR {
+				placeholders[i] = fmt.Sprintf("$%d", len(queryArgs))
+			}
+		}
+		fmt.Fprintf(&sb, " WHERE (%s) > (%s)", strings.Join(keys, ", "), strings.Join(placeholders, ", "))
+	}
+	// One extra row is selected to know if there is a next page
+	fmt.Fprintf(&sb, " ORDER BY %s LIMIT %d", strings.Join(keys, ", "), page.Size+1)
+
+	if err := o.sql.SelectContext(ctx, dst, sb.String(), queryArgs...); err != nil {
+		return "", err
+	}
+
+	rows := reflect.Indirect(reflect.ValueOf(dst))
+	if rows.Len() <= page.Size {
+		return "", nil
+	}
+	rows.SetLen(page.Size)
+
+	last := reflect.Indirect(rows.Index(page.Size - 1))
+	tm := o.sql.Mapper.TypeMap(last.Type())
+	values := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		fi, ok := tm.Names[key]
+		if !ok {
+			return "", fmt.Errorf("key %q is not a field of %s", key, last.Type())
+		}
+		values = append(values, last.FieldByIndex(fi.Index).Interface())
+	}
+
+	return o.encodePageToken(stmt, pageToken{Keys: values})
+}
+
+// encodePageToken encrypts the token with AES-GCM so that it can be handed out to clients,
+// the hash of the statement is authenticated so a token can't be used on a different query
+func (o *DB) encodePageToken(stmt string, token pageToken) (string, error) {
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return "", fmt.Errorf("marshal page token: %w", err)
+	}
+
+	aead, err := o.pageTokenAEAD()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(payload)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("page token nonce: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, payload, stmtHash(stmt))), nil
+}
+
+func (o *DB) decodePageToken(s string, stmt string, token *pageToken) error {
+	sealed, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return ErrInvalidPageToken
+	}
+	aead, err := o.pageTokenAEAD()
+	if err != nil {
+		return err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return ErrInvalidPageToken
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	payload, err := aead.Open(nil, nonce, ciphertext, stmtHash(stmt))
+	if err != nil {
+		return ErrInvalidPageToken
+	}
+
+	if err := json.Unmarshal(payload, token); err != nil {
+		return ErrInvalidPageToken
+	}
+	return nil
+}
+
+// pageTokenAEAD uses the sha256 of PageTokenKey as the AES-256 key so the key can be of any length
+func (o *DB) pageTokenAEAD() (cipher.AEAD, error) {
+	if len(o.PageTokenKey) == 0 {
+		return nil, ErrNoPageTokenKey
+	}
+	key := sha256.Sum256(o.PageTokenKey)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("page token cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func stmtHash(stmt string) []byte {
+	sum := sha256.Sum256([]byte(stmt))
+	return sum[:8]
+}
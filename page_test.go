@@ -0,0 +1,150 @@
+package sql
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDB_decodePageToken(t *testing.T) {
+	db := &DB{PageTokenKey: []byte("test_key")}
+	stmt := "SELECT * FROM users WHERE group_id = ?"
+
+	createdAt := time.Date(2022, 8, 1, 12, 30, 0, 0, time.UTC)
+	valid, err := db.encodePageToken(stmt, pageToken{Keys: []interface{}{"c52hsocs70r9j6qad7jg", int64(1 << 60), createdAt, []byte{0, 1, 2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		db      *DB
+		token   string
+		stmt    string
+		want    []interface{}
+		wantErr error
+	}{
+		{
+			name:  "should pass",
+			db:    db,
+			token: valid,
+			stmt:  stmt,
+			want:  []interface{}{"c52hsocs70r9j6qad7jg", int64(1 << 60), createdAt, []byte{0, 1, 2}},
+		},
+		{
+			name:    "should fail; tampered payload",
+			db:      db,
+			token:   tamper(valid),
+			stmt:    stmt,
+			wantErr: ErrInvalidPageToken,
+		},
+		{
+			name:    "should fail; different key",
+			db:      &DB{PageTokenKey: []byte("other_key")},
+			token:   valid,
+			stmt:    stmt,
+			wantErr: ErrInvalidPageToken,
+		},
+		{
+			name:    "should fail; different statement",
+			db:      db,
+			token:   valid,
+			stmt:    "SELECT * FROM user_groups WHERE group_id = ?",
+			wantErr: ErrInvalidPageToken,
+		},
+		{
+			name:    "should fail; no key",
+			db:      &DB{},
+			token:   valid,
+			stmt:    stmt,
+			wantErr: ErrNoPageTokenKey,
+		},
+		{
+			name:    "should fail; garbage",
+			db:      db,
+			token:   "not-a-token",
+			stmt:    stmt,
+			wantErr: ErrInvalidPageToken,
+		},
+	}
+	if sealed, _ := base64.RawURLEncoding.DecodeString(valid); strings.Contains(string(sealed), "c52hsocs70r9j6qad7jg") {
+		t.Errorf("DB.encodePageToken() = %s, the keys can be read", valid)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got pageToken
+			err := tt.db.decodePageToken(tt.token, tt.stmt, &got)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DB.decodePageToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !cmp.Equal([]interface{}(got.Keys), tt.want) {
+				t.Error(cmp.Diff([]interface{}(got.Keys), tt.want))
+			}
+		})
+	}
+}
+
+func TestDB_encodePageToken(t *testing.T) {
+	db := &DB{PageTokenKey: []byte("test_key")}
+	id := "c52hsocs70r9j6qad7jg"
+	tests := []struct {
+		name    string
+		keys    []interface{}
+		want    []interface{}
+		wantErr bool
+	}{
+		{
+			name: "should pass; scalar keys keep their type",
+			keys: []interface{}{int32(7), uint8(3), float32(1.5), true, &id},
+			want: []interface{}{int64(7), uint64(3), float64(1.5), true, id},
+		},
+		{
+			name:    "should fail; struct key",
+			keys:    []interface{}{struct{ ID int }{ID: 1}},
+			wantErr: true,
+		},
+		{
+			name:    "should fail; nil key",
+			keys:    []interface{}{nil},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := db.encodePageToken("SELECT * FROM users", pageToken{Keys: tt.keys})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DB.encodePageToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var got pageToken
+			if err := db.decodePageToken(token, "SELECT * FROM users", &got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, []interface{}(got.Keys)); diff != "" {
+				t.Errorf("keys mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// tamper flips the last byte of the sealed token
+func tamper(token string) string {
+	b, _ := base64.RawURLEncoding.DecodeString(token)
+	b[len(b)-1] ^= 1
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func TestDB_SelectPage_noPageTokenKey(t *testing.T) {
+	db := &DB{}
+	var rows []map[string]interface{}
+	if _, err := db.SelectPage(context.Background(), &rows, "SELECT * FROM users", nil, nil, PageRequest{Size: 10}); !errors.Is(err, ErrNoPageTokenKey) {
+		t.Errorf("DB.SelectPage() error = %v, want %v", err, ErrNoPageTokenKey)
+	}
+}
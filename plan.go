@@ -0,0 +1,172 @@
+package sql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Plan is what Up would apply, without applying it. With MigrateDryRun the Migrator writes the Plan of
+// Down, Steps and Goto as well, then Down is set.
+type Plan struct {
+	// Version is the current version, -1 when no migration has been applied
+	Version int `json:"version"`
+	// Dirty is set when the current version failed half way, it has to be forced before Up can run
+	Dirty bool `json:"dirty"`
+	// Down is set when the migrations are reverted
+	Down bool `json:"down,omitempty"`
+	// Migrations are the pending up migrations, or the down migrations, in the order they run
+	Migrations []PlannedMigration `json:"migrations"`
+}
+
+// PlannedMigration is a pending up migration, a go migration or a version without a down file has no checksum or contents
+type PlannedMigration struct {
+	Version    uint   `json:"version"`
+	Identifier string `json:"identifier"`
+	// Checksum is the hex sha256 of the contents
+	Checksum string `json:"checksum"`
+	Contents string `json:"contents"`
+}
+
+// Plan reads the current version and the pending up migrations from the source
+func (m *Migrator) Plan() (Plan, error) {
+	return m.plan(func(current int, v uint) bool { return int(v) > current }, false)
+}
+
+// planSteps is the plan of Steps, the next n pending migrations or the last -n applied ones
+func (m *Migrator) planSteps(n int) (Plan, error) {
+	plan, err := m.Plan()
+	if n < 0 {
+		n = -n
+		plan, err = m.plan(func(current int, v uint) bool { return int(v) <= current }, true)
+	}
+	if err != nil {
+		return Plan{}, err
+	}
+	if n < len(plan.Migrations) {
+		plan.Migrations = plan.Migrations[:n]
+	}
+	return plan, nil
+}
+
+// planGoto is the plan of Goto, the pending migrations up to the version or the applied ones after it
+func (m *Migrator) planGoto(version uint) (Plan, error) {
+	versions, err := m.versions()
+	if err != nil {
+		return Plan{}, err
+	}
+	if i := sort.Search(len(versions), func(i int) bool { return versions[i] >= version }); i == len(versions) || versions[i] != version {
+		return Plan{}, fmt.Errorf("migrations goto: version %d: %w", version, os.ErrNotExist)
+	}
+
+	current, _, err := m.Version()
+	if errors.Is(err, ErrNilVersion) || (err == nil && current <= version) {
+		return m.plan(func(current int, v uint) bool { return int(v) > current && v <= version }, false)
+	}
+	if err != nil {
+		return Plan{}, err
+	}
+	return m.plan(func(current int, v uint) bool { return int(v) <= current && v > version }, true)
+}
+
+// plan reads the migrations of the versions that match, the down migrations are read from the last version
+func (m *Migrator) plan(match func(current int, version uint) bool, down bool) (Plan, error) {
+	out := Plan{Version: -1, Down: down, Migrations: []PlannedMigration{}}
+
+	version, dirty, err := m.Version()
+	switch {
+	case errors.Is(err, ErrNilVersion):
+	case err != nil:
+		return Plan{}, err
+	default:
+		out.Version, out.Dirty = int(version), dirty
+	}
+
+	versions, err := m.versions()
+	if err != nil {
+		return Plan{}, err
+	}
+	if down {
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+	}
+	for _, v := range versions {
+		if !match(out.Version, v) {
+			continue
+		}
+		migration, err := m.read(v, down)
+		if err != nil {
+			return Plan{}, err
+		}
+		out.Migrations = append(out.Migrations, migration)
+	}
+	return out, nil
+}
+
+func (m *Migrator) read(version uint, down bool) (PlannedMigration, error) {
+	op, read := "up", m.source.ReadUp
+	if down {
+		op, read = "down", m.source.ReadDown
+	}
+	r, identifier, err := read(version)
+	if down && errors.Is(err, os.ErrNotExist) {
+		// golang-migrate only sets the version when there is no down migration
+		return PlannedMigration{Version: version}, nil
+	}
+	if err != nil {
+		return PlannedMigration{}, fmt.Errorf("read %s %d: %w", op, version, err)
+	}
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return PlannedMigration{}, fmt.Errorf("read %s %d: %w", op, version, err)
+	}
+	if _, ok := parseGoMigrationBody(b); ok {
+		return PlannedMigration{Version: version, Identifier: identifier}, nil
+	}
+	sum := sha256.Sum256(b)
+	return PlannedMigration{
+		Version:    version,
+		Identifier: identifier,
+		Checksum:   hex.EncodeToString(sum[:]),
+		Contents:   string(b),
+	}, nil
+}
+
+// String is the plan without the contents of the migrations
+func (p Plan) String() string {
+	return p.format(false)
+}
+
+// format writes a line per migration, followed by its contents indented when contents is set
+func (p Plan) format(contents bool) string {
+	var sb strings.Builder
+	switch {
+	case p.Version < 0:
+		sb.WriteString("version: none\n")
+	case p.Dirty:
+		fmt.Fprintf(&sb, "version: %d (dirty)\n", p.Version)
+	default:
+		fmt.Fprintf(&sb, "version: %d\n", p.Version)
+	}
+	if len(p.Migrations) == 0 {
+		sb.WriteString("no pending migrations\n")
+	}
+	for _, m := range p.Migrations {
+		if p.Down {
+			sb.WriteString("down ")
+		}
+		fmt.Fprintf(&sb, "%d %s sha256:%s\n", m.Version, m.Identifier, m.Checksum)
+		if contents && m.Contents != "" {
+			for _, line := range strings.Split(strings.TrimRight(m.Contents, "\n"), "\n") {
+				sb.WriteString("    " + line + "\n")
+			}
+		}
+	}
+	return sb.String()
+}
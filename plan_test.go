@@ -0,0 +1,46 @@
+package sql
+
+import (
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMigrator_Plan(t *testing.T) {
+	m := newTestMigrator(t)
+	if err := m.Steps(1); err != nil {
+		t.Fatal(err)
+	}
+
+	m.db.MigrateDryRun = true
+	m.db.MigrateDryRunOutput = io.Discard
+	if err := m.Up(); err != nil {
+		t.Fatalf("Migrator.Up() dry run error = %v", err)
+	}
+
+	got, err := m.Plan()
+	if err != nil {
+		t.Fatalf("Migrator.Plan() error = %v", err)
+	}
+	want := Plan{
+		Version: 1,
+		Migrations: []PlannedMigration{
+			{
+				Version:    2,
+				Identifier: "users",
+				Checksum:   "5b3def56e6c09e55bbf7a7dd44ff40e91cbe7422f262ef6ec3f751114d8439e3",
+				Contents:   "CREATE TABLE b (id int)",
+			},
+			{
+				Version:    5,
+				Identifier: "groups",
+				Checksum:   "e6cfd3533d02c8424b1fa86e243cb802a9a4c232b93dad8a49892d41396fbf5e",
+				Contents:   "CREATE TABLE c (id int)",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Migrator.Plan() mismatch (-want +got):\n%s", diff)
+	}
+}
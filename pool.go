@@ -0,0 +1,92 @@
+package sql
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// defaultPoolConfig keeps a service below the default max_connections of postgres and mysql
+// with a few instances
+var defaultPoolConfig = PoolConfig{
+	MaxOpenConns:      25,
+	MaxIdleConns:      10,
+	NumConns:          2,
+	ReconnectInterval: time.Minute,
+}
+
+// The postgres and mysql connections are recycled so they follow failovers and load balancers
+const (
+	defaultConnMaxLifetime = 30 * time.Minute
+	defaultConnMaxIdleTime = 5 * time.Minute
+)
+
+// PoolConfig sizes the connection pools. The sql fields are passed to database/sql, where a negative
+// MaxOpenConns or lifetime is no limit, and the cql fields to the gocql cluster.
+//
+// A zero ConnMaxLifetime or ConnMaxIdleTime is 30 and 5 minutes for postgres and mysql, and no limit for sqlite
+// as closing the last connection of a :memory: database drops its data.
+type PoolConfig struct {
+	MaxOpenConns    int           `json:"maxOpenConns"`
+	MaxIdleConns    int           `json:"maxIdleConns"`
+	ConnMaxLifetime time.Duration `json:"connMaxLifetime"`
+	ConnMaxIdleTime time.Duration `json:"connMaxIdleTime"`
+
+	// NumConns is the number of connections per cql host
+	NumConns int `json:"numConns"`
+	// ReconnectInterval is how often cql tries to reconnect to the hosts that are down
+	ReconnectInterval time.Duration `json:"reconnectInterval"`
+}
+
+// merge sets the fields of in that are not zero
+func (p *PoolConfig) merge(in PoolConfig) {
+	if in.MaxOpenConns != 0 {
+		p.MaxOpenConns = in.MaxOpenConns
+	}
+	if in.MaxIdleConns != 0 {
+		p.MaxIdleConns = in.MaxIdleConns
+	}
+	if in.ConnMaxLifetime != 0 {
+		p.ConnMaxLifetime = in.ConnMaxLifetime
+	}
+	if in.ConnMaxIdleTime != 0 {
+		p.ConnMaxIdleTime = in.ConnMaxIdleTime
+	}
+	if in.NumConns != 0 {
+		p.NumConns = in.NumConns
+	}
+	if in.ReconnectInterval != 0 {
+		p.ReconnectInterval = in.ReconnectInterval
+	}
+}
+
+// forSource sets the default lifetimes of the source
+func (p PoolConfig) forSource(source DBSource) PoolConfig {
+	if source == DBSource_sqlite {
+		return p
+	}
+	if p.ConnMaxLifetime == 0 {
+		p.ConnMaxLifetime = defaultConnMaxLifetime
+	}
+	if p.ConnMaxIdleTime == 0 {
+		p.ConnMaxIdleTime = defaultConnMaxIdleTime
+	}
+	return p
+}
+
+func (p PoolConfig) applySQL(db *sql.DB) {
+	db.SetMaxOpenConns(p.MaxOpenConns)
+	db.SetMaxIdleConns(p.MaxIdleConns)
+	db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+}
+
+func (p PoolConfig) applyCQL(cluster *gocql.ClusterConfig) {
+	if p.NumConns > 0 {
+		cluster.NumConns = p.NumConns
+	}
+	if p.ReconnectInterval != 0 {
+		cluster.ReconnectInterval = p.ReconnectInterval
+	}
+}
@@ -0,0 +1,96 @@
+package sql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWithPool(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []Option
+		want PoolConfig
+	}{
+		{
+			name: "should pass; defaults",
+			want: defaultPoolConfig,
+		},
+		{
+			name: "should pass; zero fields keep the defaults",
+			in: []Option{
+				WithPool(PoolConfig{MaxOpenConns: 100, ConnMaxLifetime: -1}),
+				WithPool(PoolConfig{NumConns: 4}),
+			},
+			want: PoolConfig{
+				MaxOpenConns:      100,
+				MaxIdleConns:      10,
+				ConnMaxLifetime:   -1,
+				NumConns:          4,
+				ReconnectInterval: time.Minute,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewConfig(tt.in...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got.Pool); diff != "" {
+				t.Errorf("NewConfig().Pool mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPoolConfig_forSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		pool   PoolConfig
+		source DBSource
+		want   PoolConfig
+	}{
+		{
+			name:   "should pass; postgres default lifetimes",
+			pool:   PoolConfig{MaxOpenConns: 25},
+			source: DBSource_postgres,
+			want:   PoolConfig{MaxOpenConns: 25, ConnMaxLifetime: defaultConnMaxLifetime, ConnMaxIdleTime: defaultConnMaxIdleTime},
+		},
+		{
+			name:   "should pass; mysql lifetime without limit",
+			pool:   PoolConfig{ConnMaxLifetime: -1},
+			source: DBSource_mysql,
+			want:   PoolConfig{ConnMaxLifetime: -1, ConnMaxIdleTime: defaultConnMaxIdleTime},
+		},
+		{
+			name:   "should pass; sqlite keeps its connections",
+			pool:   PoolConfig{MaxOpenConns: 25},
+			source: DBSource_sqlite,
+			want:   PoolConfig{MaxOpenConns: 25},
+		},
+		{
+			name:   "should pass; sqlite lifetime",
+			pool:   PoolConfig{ConnMaxLifetime: time.Hour},
+			source: DBSource_sqlite,
+			want:   PoolConfig{ConnMaxLifetime: time.Hour},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, tt.pool.forSource(tt.source)); diff != "" {
+				t.Errorf("PoolConfig.forSource() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPoolConfig_applyCQL(t *testing.T) {
+	cluster := gocql.NewCluster("127.0.0.1")
+	PoolConfig{NumConns: 3, ReconnectInterval: time.Second}.applyCQL(cluster)
+	if cluster.NumConns != 3 || cluster.ReconnectInterval != time.Second {
+		t.Errorf("PoolConfig.applyCQL() = %d, %s", cluster.NumConns, cluster.ReconnectInterval)
+	}
+}
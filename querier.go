@@ -0,0 +1,16 @@
+package sql
+
+// Querier is the query api of DB. Depend on it instead of *DB to replace the database with sqlmock in tests.
+type Querier interface {
+	Select(dst interface{}, stmt string, names []string, args interface{}) error
+	Get(dst interface{}, stmt string, names []string, args interface{}) error
+	Exec(stmt string, names []string, args interface{}) error
+	ExecMap(stmt string, names []string, args map[string]interface{}) error
+	ExecMany(stmt string, names []string, args ...interface{}) error
+	Queryx(stmt string, names []string, args ...interface{}) (ScannerIterator, error)
+	QueryRow(stmt string, args ...interface{}) Scanner
+	WriteBatch(queries []string, namesForSrcs [][]string, srcs []interface{}, opts ...BatchOption) error
+	Ping() error
+}
+
+var _ Querier = (*DB)(nil)
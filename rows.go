@@ -0,0 +1,126 @@
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/gocql/gocql"
+	"github.com/jmoiron/sqlx"
+	cqlreflectx "github.com/scylladb/go-reflectx"
+	"github.com/scylladb/gocqlx/v2"
+)
+
+// Rows is a streaming result set. Next must be called before every scan,
+// once Next returns false Err reports why the iteration stopped.
+// Close must be called when the rows are not fully consumed.
+type Rows interface {
+	Next() bool
+	Scanner
+	StructScan(dest interface{}) error
+	MapScan(dest map[string]interface{}) error
+	Columns() ([]string, error)
+	Err() error
+	Close() error
+}
+
+var _ Rows = (*sqlx.Rows)(nil)
+var _ Rows = (*cqlRows)(nil)
+
+// cqlRows adapts a gocqlx query to Rows, the query is released when the rows are closed
+type cqlRows struct {
+	query   *gocqlx.Queryx
+	iter    *gocql.Iter
+	scanner gocql.Scanner
+	mapper  *cqlreflectx.Mapper
+	closed  bool
+	err     error
+}
+
+func newCQLRows(query *gocqlx.Queryx) *cqlRows {
+	iter := query.Query.Iter()
+	return &cqlRows{
+		query:   query,
+		iter:    iter,
+		scanner: iter.Scanner(),
+		mapper:  query.Mapper,
+	}
+}
+
+func (r *cqlRows) Next() bool {
+	if r.closed {
+		return false
+	}
+	if r.scanner.Next() {
+		return true
+	}
+	r.err = r.Close()
+	return false
+}
+
+func (r *cqlRows) Scan(dest ...interface{}) error {
+	if r.closed {
+		return errors.New("rows are closed")
+	}
+	return r.scanner.Scan(dest...)
+}
+
+func (r *cqlRows) StructScan(dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("expected a pointer to a struct, got %T", dest)
+	}
+	v = reflect.Indirect(v)
+
+	columns, _ := r.Columns()
+	traversals := r.mapper.TraversalsByName(v.Type(), columns)
+	values := make([]interface{}, len(columns))
+	for i, traversal := range traversals {
+		if len(traversal) == 0 {
+			return fmt.Errorf("missing destination name %q in %T", columns[i], dest)
+		}
+		values[i] = cqlreflectx.FieldByIndexes(v, traversal).Addr().Interface()
+	}
+
+	return r.Scan(values...)
+}
+
+func (r *cqlRows) MapScan(dest map[string]interface{}) error {
+	columns := r.iter.Columns()
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		values[i] = column.TypeInfo.New()
+	}
+
+	if err := r.Scan(values...); err != nil {
+		return err
+	}
+
+	for i, column := range columns {
+		dest[column.Name] = reflect.Indirect(reflect.ValueOf(values[i])).Interface()
+	}
+	return nil
+}
+
+func (r *cqlRows) Columns() ([]string, error) {
+	columns := r.iter.Columns()
+	out := make([]string, 0, len(columns))
+	for _, column := range columns {
+		out = append(out, column.Name)
+	}
+	return out, nil
+}
+
+func (r *cqlRows) Err() error {
+	return r.err
+}
+
+func (r *cqlRows) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	err := r.scanner.Err()
+	r.query.Release()
+	return err
+}
@@ -0,0 +1,83 @@
+//go:build sqlite
+// +build sqlite
+
+package sql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDB_QueryxRows_sqlite(t *testing.T) {
+	db := newTestSQLite(t)
+	if err := db.Exec("INSERT INTO users (id, name) VALUES (?, ?)", []string{"id", "name"}, contextTestUser{ID: 2, Name: "jane"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		stmt    string
+		names   []string
+		args    []interface{}
+		want    []contextTestUser
+		wantErr bool
+	}{
+		{
+			name:  "should pass; names bind the args in order",
+			stmt:  "SELECT id, name FROM users WHERE id = ? OR name = ? ORDER BY id",
+			names: []string{"id", "name"},
+			args:  []interface{}{1, "jane"},
+			want:  []contextTestUser{{ID: 1, Name: "joe"}, {ID: 2, Name: "jane"}},
+		},
+		{
+			name: "should pass; without names the args are positional",
+			stmt: "SELECT id, name FROM users WHERE id = ?",
+			args: []interface{}{2},
+			want: []contextTestUser{{ID: 2, Name: "jane"}},
+		},
+		{
+			name:    "should fail; more names than args",
+			stmt:    "SELECT id, name FROM users WHERE id = ? OR name = ?",
+			names:   []string{"id", "name"},
+			args:    []interface{}{1},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := db.QueryxRows(context.Background(), tt.stmt, tt.names, tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("QueryxRows() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer rows.Close()
+			var got []contextTestUser
+			for rows.Next() {
+				var u contextTestUser
+				if err := rows.StructScan(&u); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, u)
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("QueryxRows() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	iter, err := db.Queryx("SELECT id FROM users WHERE id = ?", []string{"id"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := iter.(Rows); !ok {
+		t.Errorf("Queryx() = %T, want a Rows", iter)
+	}
+	iter.(Rows).Close()
+}
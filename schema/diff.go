@@ -0,0 +1,182 @@
+// Package schema compares the desired tables with the tables of a database and
+// renders the difference as a migration.
+package schema
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	sql "github.com/joematpal/go-sql/v2"
+	"github.com/joematpal/go-sql/v2/table"
+)
+
+// MigrationsTable is the table golang-migrate keeps the version in, it is never part of a diff
+// and neither is sql.CQLMigrationsLockTable
+const MigrationsTable = "schema_migrations"
+
+// Diff is the change from the tables of a database to the desired tables
+type Diff struct {
+	Dialect table.Dialect `json:"dialect"`
+	// AddedTables are desired but missing from the database
+	AddedTables []table.Table `json:"addedTables,omitempty"`
+	// RemovedTables are in the database but not desired
+	RemovedTables []table.Table `json:"removedTables,omitempty"`
+	// ChangedTables are in both but their columns or keys differ
+	ChangedTables []TableDiff `json:"changedTables,omitempty"`
+}
+
+// TableDiff is the change to the columns of a table
+type TableDiff struct {
+	Name string `json:"name"`
+	// From is the table in the database and To is the desired table
+	From table.Table `json:"from"`
+	To   table.Table `json:"to"`
+
+	AddedColumns   []string       `json:"addedColumns,omitempty"`
+	RemovedColumns []string       `json:"removedColumns,omitempty"`
+	ChangedColumns []ColumnChange `json:"changedColumns,omitempty"`
+	// KeyChanged is set when the primary key differs, which can't be altered in place
+	KeyChanged bool `json:"keyChanged,omitempty"`
+}
+
+// ColumnChange is a column whose type or nullability differs
+type ColumnChange struct {
+	Name string       `json:"name"`
+	From table.Column `json:"from"`
+	To   table.Column `json:"to"`
+}
+
+// IsEmpty reports if there is nothing to migrate
+func (d Diff) IsEmpty() bool {
+	return len(d.AddedTables) == 0 && len(d.RemovedTables) == 0 && len(d.ChangedTables) == 0
+}
+
+// DiffDB describes the tables of db and compares them to the desired tables
+func DiffDB(ctx context.Context, db *sql.DB, desired ...table.Table) (Diff, error) {
+	tables, err := db.ListTables(ctx)
+	if err != nil {
+		return Diff{}, err
+	}
+
+	actual := make([]table.Table, 0, len(tables))
+	for _, t := range tables {
+		if t.Name != MigrationsTable && t.Name != sql.CQLMigrationsLockTable {
+			actual = append(actual, t)
+		}
+	}
+	return DiffTables(db.DBSource.Dialect(), actual, desired), nil
+}
+
+// DiffTables compares the actual tables to the desired tables.
+// Types and nullability are only compared when both columns have a definition.
+func DiffTables(d table.Dialect, actual, desired []table.Table) Diff {
+	out := Diff{Dialect: d}
+
+	from := byName(actual)
+	to := byName(desired)
+
+	for _, name := range sortedNames(to) {
+		t, ok := from[name]
+		if !ok {
+			out.AddedTables = append(out.AddedTables, to[name])
+			continue
+		}
+		if td := diffTable(d, t, to[name]); td != nil {
+			out.ChangedTables = append(out.ChangedTables, *td)
+		}
+	}
+	for _, name := range sortedNames(from) {
+		if _, ok := to[name]; !ok {
+			out.RemovedTables = append(out.RemovedTables, from[name])
+		}
+	}
+
+	return out
+}
+
+func diffTable(d table.Dialect, from, to table.Table) *TableDiff {
+	td := TableDiff{
+		Name:       to.Name,
+		From:       from,
+		To:         to,
+		KeyChanged: !equalStrings(from.PrimaryKey(), to.PrimaryKey()),
+	}
+	if d == table.Dialect_cql {
+		// The partition and clustering keys make the same primary key with a different layout
+		td.KeyChanged = td.KeyChanged || !equalStrings(from.PartitionKey, to.PartitionKey)
+	}
+
+	keys := map[string]bool{}
+	for _, key := range to.PrimaryKey() {
+		keys[key] = true
+	}
+
+	for _, name := range to.SortedColumns() {
+		if _, ok := from.Columns[name]; !ok {
+			td.AddedColumns = append(td.AddedColumns, name)
+			continue
+		}
+
+		fromDef, fromOK := from.Definitions[name]
+		toDef, toOK := to.Definitions[name]
+		if !fromOK || !toOK {
+			continue
+		}
+		nullable := fromDef.Nullable != toDef.Nullable
+		// sqlite only reports a not null key when it was declared as not null
+		if d == table.Dialect_sqlite && keys[name] {
+			nullable = false
+		}
+		if !d.SameType(fromDef.Type, toDef.Type) || nullable {
+			td.ChangedColumns = append(td.ChangedColumns, ColumnChange{Name: name, From: fromDef, To: toDef})
+		}
+	}
+	for _, name := range from.SortedColumns() {
+		if _, ok := to.Columns[name]; !ok {
+			td.RemovedColumns = append(td.RemovedColumns, name)
+		}
+	}
+
+	if len(td.AddedColumns) == 0 && len(td.RemovedColumns) == 0 && len(td.ChangedColumns) == 0 && !td.KeyChanged {
+		return nil
+	}
+	return &td
+}
+
+func byName(tables []table.Table) map[string]table.Table {
+	out := make(map[string]table.Table, len(tables))
+	for _, t := range tables {
+		out[t.Name] = t
+	}
+	return out
+}
+
+func sortedNames(tables map[string]table.Table) []string {
+	out := make([]string, 0, len(tables))
+	for name := range tables {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func columnDefinition(t table.Table, name string) (table.Column, error) {
+	def, ok := t.Definitions[name]
+	if !ok || def.Type == "" {
+		return table.Column{}, fmt.Errorf("column %s.%s has no type", t.Name, name)
+	}
+	return def, nil
+}
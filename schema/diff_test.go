@@ -0,0 +1,272 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/joematpal/go-sql/v2/table"
+	test_users "github.com/joematpal/go-sql/v2/test/users"
+)
+
+var testGroups = table.Table{
+	Name:    "groups",
+	Columns: map[string]struct{}{"group_id": {}, "name": {}},
+	Definitions: map[string]table.Column{
+		"group_id": {Type: "text"},
+		"name":     {Type: "text", Nullable: true},
+	},
+	PartitionKey: []string{"group_id"},
+}
+
+var testUsers = table.Table{
+	Name:    "users",
+	Columns: map[string]struct{}{"user_id": {}, "email": {}, "verified": {}},
+	Definitions: map[string]table.Column{
+		"user_id":  {Type: "character(20)"},
+		"email":    {Type: "text", Nullable: true},
+		"verified": {Type: "boolean", Nullable: true},
+	},
+	PartitionKey: []string{"user_id"},
+}
+
+var testDesiredUsers = table.Table{
+	Name:    "users",
+	Columns: map[string]struct{}{"user_id": {}, "email": {}, "created_at": {}},
+	Definitions: map[string]table.Column{
+		"user_id":    {Type: "character(20)"},
+		"email":      {Type: "text"},
+		"created_at": {Type: "bigint", Nullable: true},
+	},
+	PartitionKey: []string{"user_id"},
+}
+
+func TestDiffTables(t *testing.T) {
+	type args struct {
+		d       table.Dialect
+		actual  []table.Table
+		desired []table.Table
+	}
+	tests := []struct {
+		name string
+		args args
+		want Diff
+	}{
+		{
+			name: "should pass; no changes",
+			args: args{
+				d:       table.Dialect_postgres,
+				actual:  []table.Table{testUsers},
+				desired: []table.Table{testUsers},
+			},
+			want: Diff{Dialect: table.Dialect_postgres},
+		},
+		{
+			name: "should pass; added, removed and changed",
+			args: args{
+				d:       table.Dialect_postgres,
+				actual:  []table.Table{testUsers},
+				desired: []table.Table{testGroups, testDesiredUsers},
+			},
+			want: Diff{
+				Dialect:     table.Dialect_postgres,
+				AddedTables: []table.Table{testGroups},
+				ChangedTables: []TableDiff{{
+					Name:           "users",
+					From:           testUsers,
+					To:             testDesiredUsers,
+					AddedColumns:   []string{"created_at"},
+					RemovedColumns: []string{"verified"},
+					ChangedColumns: []ColumnChange{{
+						Name: "email",
+						From: table.Column{Type: "text", Nullable: true},
+						To:   table.Column{Type: "text"},
+					}},
+				}},
+			},
+		},
+		{
+			name: "should pass; removed table and aliased types",
+			args: args{
+				d: table.Dialect_mysql,
+				actual: []table.Table{testGroups, {
+					Name:        "flags",
+					Columns:     map[string]struct{}{"on": {}},
+					Definitions: map[string]table.Column{"on": {Type: "tinyint(1)"}},
+				}},
+				desired: []table.Table{{
+					Name:        "flags",
+					Columns:     map[string]struct{}{"on": {}},
+					Definitions: map[string]table.Column{"on": {Type: "boolean"}},
+				}},
+			},
+			want: Diff{
+				Dialect:       table.Dialect_mysql,
+				RemovedTables: []table.Table{testGroups},
+			},
+		},
+		{
+			name: "should pass; cql key layout",
+			args: args{
+				d:       table.Dialect_cql,
+				actual:  []table.Table{testGroups.WithKeys([]string{"group_id", "name"})},
+				desired: []table.Table{testGroups.WithKeys([]string{"group_id"}, "name")},
+			},
+			want: Diff{
+				Dialect: table.Dialect_cql,
+				ChangedTables: []TableDiff{{
+					Name:       "groups",
+					From:       testGroups.WithKeys([]string{"group_id", "name"}),
+					To:         testGroups.WithKeys([]string{"group_id"}, "name"),
+					KeyChanged: true,
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffTables(tt.args.d, tt.args.actual, tt.args.desired)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("DiffTables() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiff_Up(t *testing.T) {
+	tests := []struct {
+		name     string
+		diff     Diff
+		wantUp   string
+		wantDown string
+		wantErr  bool
+	}{
+		{
+			name: "should pass; postgres",
+			diff: DiffTables(table.Dialect_postgres, []table.Table{testUsers}, []table.Table{testGroups, testDesiredUsers}),
+			wantUp: `CREATE TABLE IF NOT EXISTS "groups" (
+    "group_id" text NOT NULL,
+    "name" text,
+    PRIMARY KEY ("group_id")
+);
+ALTER TABLE "users" ADD COLUMN "created_at" bigint;
+ALTER TABLE "users" ALTER COLUMN "email" SET NOT NULL;
+ALTER TABLE "users" DROP COLUMN "verified";
+`,
+			wantDown: `ALTER TABLE "users" ADD COLUMN "verified" boolean;
+ALTER TABLE "users" ALTER COLUMN "email" DROP NOT NULL;
+ALTER TABLE "users" DROP COLUMN "created_at";
+DROP TABLE IF EXISTS "groups";
+`,
+		},
+		{
+			name: "should pass; cql",
+			diff: Diff{
+				Dialect:     table.Dialect_cql,
+				AddedTables: []table.Table{testGroups.WithKeys([]string{"group_id", "name"})},
+				ChangedTables: []TableDiff{{
+					Name:           "users",
+					To:             testDesiredUsers,
+					AddedColumns:   []string{"created_at"},
+					ChangedColumns: []ColumnChange{{Name: "email", From: table.Column{Type: "text"}, To: table.Column{Type: "int"}}},
+				}},
+			},
+			wantUp: `CREATE TABLE IF NOT EXISTS "groups" (
+    "group_id" text,
+    "name" text,
+    PRIMARY KEY (("group_id", "name"))
+);
+ALTER TABLE "users" ADD "created_at" bigint;
+-- users.email can't be altered in place from text not null to int not null
+`,
+			wantDown: `-- users.email can't be altered in place from int not null to text not null
+ALTER TABLE "users" DROP "created_at";
+DROP TABLE IF EXISTS "groups";
+`,
+		},
+		{
+			name: "should pass; mysql string key",
+			diff: DiffTables(table.Dialect_mysql, nil, []table.Table{mustFromStruct[test_users.User](t, "users", table.Dialect_mysql)}),
+			wantUp: "CREATE TABLE IF NOT EXISTS `users` (\n" +
+				"    `created_at` bigint,\n" +
+				"    `created_by` text,\n" +
+				"    `email` text,\n" +
+				"    `updated_at` bigint,\n" +
+				"    `updated_by` text,\n" +
+				"    `user_id` varchar(255) NOT NULL,\n" +
+				"    `username` text,\n" +
+				"    `verified` boolean,\n" +
+				"    PRIMARY KEY (`user_id`)\n" +
+				");\n",
+			wantDown: "DROP TABLE IF EXISTS `users`;\n",
+		},
+		{
+			name: "should fail; column without a type",
+			diff: Diff{
+				Dialect:     table.Dialect_mysql,
+				AddedTables: []table.Table{table.New("logs", map[string]struct{}{"line": {}})},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up, err := tt.diff.Up()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Diff.Up() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.wantUp, up); diff != "" {
+				t.Errorf("Diff.Up() mismatch (-want +got):\n%s", diff)
+			}
+			down, err := tt.diff.Down()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Diff.Down() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.wantDown, down); diff != "" {
+				t.Errorf("Diff.Down() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiff_WriteMigration(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"01_init.up.sql", "09_users.up.sql", "09_users.down.sql", "README.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("--"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diff := DiffTables(table.Dialect_postgres, nil, []table.Table{testGroups})
+	up, down, err := diff.WriteMigration(dir, "groups")
+	if err != nil {
+		t.Fatalf("Diff.WriteMigration() error = %v", err)
+	}
+	if want := filepath.Join(dir, "10_groups.up.sql"); up != want {
+		t.Errorf("Diff.WriteMigration() up = %v, want %v", up, want)
+	}
+	if want := filepath.Join(dir, "10_groups.down.sql"); down != want {
+		t.Errorf("Diff.WriteMigration() down = %v, want %v", down, want)
+	}
+	b, err := os.ReadFile(down)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "DROP TABLE IF EXISTS \"groups\";\n"; string(b) != want {
+		t.Errorf("Diff.WriteMigration() down content = %q, want %q", b, want)
+	}
+
+	if _, _, err := (Diff{Dialect: table.Dialect_postgres}).WriteMigration(dir, "empty"); err != ErrNoChanges {
+		t.Errorf("Diff.WriteMigration() error = %v, want %v", err, ErrNoChanges)
+	}
+}
+
+func mustFromStruct[T any](t *testing.T, name string, d table.Dialect) table.Table {
+	t.Helper()
+	out, err := table.FromStruct[T](name, table.WithDialect(d))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
@@ -0,0 +1,118 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/joematpal/go-sql/v2/table"
+)
+
+// ErrNoChanges is returned when writing a migration for an empty diff
+var ErrNoChanges = errors.New("no schema changes")
+
+// minVersionWidth is the width of the versions in database/, ie. 01_init.up.sql
+const minVersionWidth = 2
+
+var migrationFileRegex = regexp.MustCompile(`^([0-9]+)_.*\.(up|down)\.[a-z]+$`)
+
+// Extension is the extension of the migration files for the dialect
+func Extension(d table.Dialect) string {
+	if d == table.Dialect_cql {
+		return ".cql"
+	}
+	return ".sql"
+}
+
+// NextVersion returns the version after the highest migration in fsys and the width
+// the version should be padded to, so the files keep sorting by name
+func NextVersion(fsys fs.FS) (int, int, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, 0, err
+	}
+
+	version, width := 0, minVersionWidth
+	for _, entry := range entries {
+		m := migrationFileRegex.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			continue
+		}
+		v, err := strconv.Atoi(m[1])
+		if err != nil {
+			return 0, 0, fmt.Errorf("version of %s: %w", entry.Name(), err)
+		}
+		if v > version {
+			version = v
+		}
+		if len(m[1]) > width {
+			width = len(m[1])
+		}
+	}
+	return version + 1, width, nil
+}
+
+// MigrationFileNames returns the up and down file names for the version
+func MigrationFileNames(d table.Dialect, version, width int, name string) (string, string) {
+	base := fmt.Sprintf("%0*d_%s", width, version, name)
+	ext := Extension(d)
+	return base + ".up" + ext, base + ".down" + ext
+}
+
+// WriteMigration renders the diff into the next numbered up and down files of dir,
+// ie. database/psql/03_add_groups.up.sql, and returns their paths
+func (d Diff) WriteMigration(dir, name string) (string, string, error) {
+	if d.IsEmpty() {
+		return "", "", ErrNoChanges
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", "", fmt.Errorf("invalid migration name %q", name)
+	}
+
+	up, err := d.Up()
+	if err != nil {
+		return "", "", fmt.Errorf("render up: %w", err)
+	}
+	down, err := d.Down()
+	if err != nil {
+		return "", "", fmt.Errorf("render down: %w", err)
+	}
+
+	version, width, err := NextVersion(os.DirFS(dir))
+	if err != nil {
+		return "", "", fmt.Errorf("next version: %w", err)
+	}
+	upName, downName := MigrationFileNames(d.Dialect, version, width, name)
+	upPath, downPath := filepath.Join(dir, upName), filepath.Join(dir, downName)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", err
+	}
+	if err := writeNewFile(upPath, up); err != nil {
+		return "", "", err
+	}
+	if err := writeNewFile(downPath, down); err != nil {
+		os.Remove(upPath)
+		return "", "", err
+	}
+	return upPath, downPath, nil
+}
+
+// writeNewFile fails instead of overwriting an existing migration
+func writeNewFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,213 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/joematpal/go-sql/v2/table"
+)
+
+// step is one change with the statements to apply and revert it
+type step struct {
+	up   []string
+	down []string
+}
+
+// Up renders the statements that migrate the database to the desired tables
+func (d Diff) Up() (string, error) {
+	steps, err := d.steps()
+	if err != nil {
+		return "", err
+	}
+	var stmts []string
+	for _, s := range steps {
+		stmts = append(stmts, s.up...)
+	}
+	return joinStatements(stmts), nil
+}
+
+// Down renders the statements that revert Up, in the reverse order
+func (d Diff) Down() (string, error) {
+	steps, err := d.steps()
+	if err != nil {
+		return "", err
+	}
+	var stmts []string
+	for i := len(steps) - 1; i >= 0; i-- {
+		stmts = append(stmts, steps[i].down...)
+	}
+	return joinStatements(stmts), nil
+}
+
+func (d Diff) steps() ([]step, error) {
+	r := renderer{d: d.Dialect}
+	var out []step
+
+	for _, t := range d.AddedTables {
+		create, err := r.createTable(t)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, step{up: []string{create}, down: []string{r.dropTable(t)}})
+	}
+
+	for _, td := range d.ChangedTables {
+		if td.KeyChanged {
+			comment := fmt.Sprintf("-- the primary key of %s changed from (%s) to (%s), the table has to be recreated",
+				td.Name, strings.Join(td.From.PrimaryKey(), ", "), strings.Join(td.To.PrimaryKey(), ", "))
+			out = append(out, step{up: []string{comment}, down: []string{comment}})
+		}
+		for _, name := range td.AddedColumns {
+			def, err := columnDefinition(td.To, name)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, step{
+				up:   []string{r.addColumn(td.Name, name, def)},
+				down: []string{r.dropColumn(td.Name, name)},
+			})
+		}
+		for _, c := range td.ChangedColumns {
+			out = append(out, step{
+				up:   r.alterColumn(td.Name, c.Name, c.From, c.To),
+				down: r.alterColumn(td.Name, c.Name, c.To, c.From),
+			})
+		}
+		for _, name := range td.RemovedColumns {
+			def, err := columnDefinition(td.From, name)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, step{
+				up:   []string{r.dropColumn(td.Name, name)},
+				down: []string{r.addColumn(td.Name, name, def)},
+			})
+		}
+	}
+
+	for _, t := range d.RemovedTables {
+		create, err := r.createTable(t)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, step{up: []string{r.dropTable(t)}, down: []string{create}})
+	}
+
+	return out, nil
+}
+
+type renderer struct {
+	d table.Dialect
+}
+
+func (r renderer) column(name string, def table.Column) string {
+	out := r.d.Quote(name) + " " + def.Type
+	// cql has no not null columns, the keys are implicitly not null
+	if !def.Nullable && r.d != table.Dialect_cql {
+		out += " NOT NULL"
+	}
+	return out
+}
+
+func (r renderer) createTable(t table.Table) (string, error) {
+	lines := []string{}
+	for _, name := range t.SortedColumns() {
+		def, err := columnDefinition(t, name)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, r.column(name, def))
+	}
+
+	if key := r.primaryKey(t); key != "" {
+		lines = append(lines, "PRIMARY KEY ("+key+")")
+	} else if r.d == table.Dialect_cql {
+		return "", fmt.Errorf("%s: %w", t.Name, table.ErrNoPrimaryKey)
+	}
+
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n    %s\n)", r.d.Quote(t.Name), strings.Join(lines, ",\n    ")), nil
+}
+
+// primaryKey renders the key columns, a cql composite partition key is wrapped in its own parentheses
+func (r renderer) primaryKey(t table.Table) string {
+	partition := r.quoteList(t.PartitionKey)
+	if r.d == table.Dialect_cql && len(t.PartitionKey) > 1 {
+		partition = "(" + partition + ")"
+	}
+	if len(t.ClusteringKey) == 0 {
+		return partition
+	}
+	if partition == "" {
+		return r.quoteList(t.ClusteringKey)
+	}
+	return partition + ", " + r.quoteList(t.ClusteringKey)
+}
+
+func (r renderer) dropTable(t table.Table) string {
+	return "DROP TABLE IF EXISTS " + r.d.Quote(t.Name)
+}
+
+func (r renderer) addColumn(tableName, name string, def table.Column) string {
+	if r.d == table.Dialect_cql {
+		return fmt.Sprintf("ALTER TABLE %s ADD %s", r.d.Quote(tableName), r.column(name, def))
+	}
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", r.d.Quote(tableName), r.column(name, def))
+}
+
+func (r renderer) dropColumn(tableName, name string) string {
+	if r.d == table.Dialect_cql {
+		return fmt.Sprintf("ALTER TABLE %s DROP %s", r.d.Quote(tableName), r.d.Quote(name))
+	}
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", r.d.Quote(tableName), r.d.Quote(name))
+}
+
+// alterColumn changes a column in place, sqlite and cql can't so a comment is left for the change
+func (r renderer) alterColumn(tableName, name string, from, to table.Column) []string {
+	t, c := r.d.Quote(tableName), r.d.Quote(name)
+	switch r.d {
+	case table.Dialect_postgres:
+		out := []string{}
+		if !r.d.SameType(from.Type, to.Type) {
+			out = append(out, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s", t, c, to.Type))
+		}
+		if from.Nullable != to.Nullable {
+			action := "SET NOT NULL"
+			if to.Nullable {
+				action = "DROP NOT NULL"
+			}
+			out = append(out, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s", t, c, action))
+		}
+		return out
+	case table.Dialect_mysql:
+		return []string{fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s", t, r.column(name, to))}
+	}
+	return []string{fmt.Sprintf("-- %s.%s can't be altered in place from %s to %s", tableName, name, describeColumn(from), describeColumn(to))}
+}
+
+func (r renderer) quoteList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = r.d.Quote(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func describeColumn(c table.Column) string {
+	if c.Nullable {
+		return c.Type
+	}
+	return c.Type + " not null"
+}
+
+// joinStatements ends every statement with a ; except for the comments
+func joinStatements(stmts []string) string {
+	var sb strings.Builder
+	for _, stmt := range stmts {
+		sb.WriteString(stmt)
+		if !strings.HasPrefix(stmt, "--") {
+			sb.WriteString(";")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
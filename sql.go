@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"reflect"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"github.com/gocql/gocql"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/jmoiron/sqlx"
+	"github.com/joematpal/go-sql/v2/table"
 	cqlreflectx "github.com/scylladb/go-reflectx"
 	"github.com/scylladb/gocqlx/v2"
 )
@@ -37,19 +39,59 @@ type DB struct {
 	cql         *gocqlx.Session
 	mapFunc     func(string) string
 	tagMapFunc  func(string) string
+	// ownMapper is set by WithMapFunc and WithTagMapFunc, the DB does not share its connection
+	ownMapper bool
 
 	Timeout        time.Duration
 	ConnectTimeout time.Duration
 
+	// Pool sizes the sql connection pool and the cql connections per host, see WithPool
+	Pool PoolConfig `json:"pool"`
+
+	// TxRetries is how many times RunInTx retries on a serialization failure
+	TxRetries int `json:"txRetries"`
+
+	// PageTokenKey encrypts the page tokens from SelectPage, SelectPage fails with ErrNoPageTokenKey without it.
+	// Every instance that is handed the tokens needs the same key.
+	PageTokenKey []byte `json:"-"`
+
+	// StmtCacheSize is the number of prepared statements kept per connection, 0 disables the cache
+	StmtCacheSize int `json:"stmtCacheSize"`
+	stmts         *stmtCache
+
+	// registry shares the connection with the DBs of the same config, see WithRegistry
+	registry    *Registry
+	registryKey string
+	released    bool
+
+	// MigrateDialectDirs treats MigratePath as a root with a directory per DBSource, see MigrateDir
+	MigrateDialectDirs bool `json:"migrateDialectDirs"`
+	// MigrateDirs overrides the directory of a DBSource, ie. psql for postgres
+	MigrateDirs map[DBSource]string `json:"migrateDirs,omitempty"`
+	// MigrateSharedDir is used when the DBSource has no directory, it defaults to DefaultMigrateSharedDir
+	MigrateSharedDir string `json:"migrateSharedDir,omitempty"`
+	// MigrateDryRun writes the Plan of the migrations with their contents to MigrateDryRunOutput instead of running them
+	MigrateDryRun bool `json:"migrateDryRun"`
+	// MigrateDryRunOutput is where the dry run plans are written, it defaults to os.Stdout
+	MigrateDryRunOutput io.Writer `json:"-"`
+	// MigrateLockTTL is how long the cql migrations lock outlives an instance that died while migrating
+	MigrateLockTTL time.Duration `json:"migrateLockTTL"`
+	// MigrateLockTimeout is how long to wait for another instance to finish its cql migrations
+	MigrateLockTimeout time.Duration `json:"migrateLockTimeout"`
+
 	RawQuery string `json:"rawQuery"`
 
 	// CQL
 	Authenticator            gocql.Authenticator `json:"-"`
 	DisableInitialHostLookup bool                `json:"disableInitialHostLookup"`
 	Consistency              gocql.Consistency
+	// CQLPolicy picks the hosts of the queries and retries them, see WithCQLHostSelection and WithCQLRetryPolicy
+	CQLPolicy CQLPolicyConfig `json:"cqlPolicy"`
 
 	// SSL
 	CaPath string `json:"caPath"`
+	// TLS encrypts the connection of every source, see WithTLS
+	TLS TLSConfig `json:"tls"`
 }
 
 type DBSource string
@@ -67,21 +109,49 @@ func (s DBSource) String() string {
 	return string(s)
 }
 
+// Dialect is the table dialect used to generate statements for this source
+func (s DBSource) Dialect() table.Dialect {
+	return table.Dialect(s)
+}
+
+// MapName maps a json name to its column name the way the mapper of the DB maps struct tags
+func (o *DB) MapName(name string) string {
+	mapFunc := o.tagMapFunc
+	if mapFunc == nil {
+		mapFunc = cqlreflectx.CamelToSnakeASCII
+	}
+	return table.PreMapFunc(mapFunc)(name)
+}
+
 // Here it converts json that is camel case to snakecase
 // Can pass through
 func New(in ...Option) (*DB, error) {
+	opts, err := NewConfig(in...)
+	if err != nil {
+		return nil, err
+	}
+	return opts, opts.IsValid()
+}
+
+// NewConfig applies the options to the defaults of New without connecting
+func NewConfig(in ...Option) (*DB, error) {
 	opts := &DB{
-		AppEnv:      production,
-		MigratePath: "database/sql",
-		mapFunc:     cqlreflectx.CamelToSnakeASCII,
-		tagMapFunc:  cqlreflectx.CamelToSnakeASCII,
+		AppEnv:             production,
+		MigratePath:        "database/sql",
+		mapFunc:            cqlreflectx.CamelToSnakeASCII,
+		tagMapFunc:         cqlreflectx.CamelToSnakeASCII,
+		TxRetries:          defaultTxRetries,
+		StmtCacheSize:      defaultStmtCacheSize,
+		MigrateLockTTL:     defaultMigrateLockTTL,
+		MigrateLockTimeout: defaultMigrateLockTimeout,
+		Pool:               defaultPoolConfig,
 	}
 	for _, opt := range in {
 		if err := opt.applyOption(opts); err != nil {
 			return nil, err
 		}
 	}
-	return opts, opts.IsValid()
+	return opts, nil
 }
 
 func (o *DB) SQLX() (*sqlx.DB, error) {
@@ -102,21 +172,21 @@ func (o *DB) CQLX() (*gocqlx.Session, error) {
 }
 
 func (o *DB) Select(dst interface{}, stmt string, names []string, args interface{}) error {
-	switch o.DBSource {
-	case DBSource_postgres, DBSource_mysql:
-		namedStmt := ToNamedStatement(o.DBSource, stmt, names)
+	return o.SelectContext(context.Background(), dst, stmt, names, args)
+}
 
-		query, err := o.sql.PrepareNamed(namedStmt)
+// SelectContext returns many documents, the query is cancelled when ctx is done
+func (o *DB) SelectContext(ctx context.Context, dst interface{}, stmt string, names []string, args interface{}) error {
+	switch o.DBSource {
+	case DBSource_postgres, DBSource_mysql, DBSource_sqlite:
+		query, release, err := o.prepareNamed(ctx, stmt, names)
 		if err != nil {
 			return fmt.Errorf("prepare named: %w", err)
 		}
-		return query.Select(dst, args)
+		defer release()
+		return query.SelectContext(ctx, dst, args)
 	case DBSource_cql:
-		if val, ok := args.(map[string]interface{}); ok {
-			return o.cql.Query(stmt, names).BindMap(val).Select(dst)
-		} else {
-			return o.cql.Query(stmt, names).BindStruct(args).Select(dst)
-		}
+		return o.cqlReadQuery(ctx, stmt, names, args).Select(dst)
 	default:
 		return ErrNoSourceConfigured
 	}
@@ -126,17 +196,17 @@ func (o *DB) Select(dst interface{}, stmt string, names []string, args interface
 // Deprecated
 func (o *DB) SelectFromMap(dst interface{}, stmt string, names []string, args map[string]interface{}) error {
 	switch o.DBSource {
-	case DBSource_postgres, DBSource_mysql:
-		namedStmt := ToNamedStatement(o.DBSource, stmt, names)
-
-		query, err := o.sql.PrepareNamed(namedStmt)
+	case DBSource_postgres, DBSource_mysql, DBSource_sqlite:
+		query, release, err := o.prepareNamed(context.Background(), stmt, names)
 		if err != nil {
 			return fmt.Errorf("prepare named: %w", err)
 		}
-		defer query.Close()
+		defer release()
 		return query.Select(dst, args)
 	case DBSource_cql:
-		return o.cql.Query(stmt, names).BindMap(args).Select(dst)
+		query := o.cql.Query(stmt, names).BindMap(args)
+		o.speculate(query.Query)
+		return query.Select(dst)
 	default:
 		return ErrNoSourceConfigured
 	}
@@ -144,19 +214,21 @@ func (o *DB) SelectFromMap(dst interface{}, stmt string, names []string, args ma
 
 // Returns one document
 func (o *DB) Get(dst interface{}, stmt string, names []string, args interface{}) error {
+	return o.GetContext(context.Background(), dst, stmt, names, args)
+}
+
+// GetContext returns one document
+func (o *DB) GetContext(ctx context.Context, dst interface{}, stmt string, names []string, args interface{}) error {
 	switch o.DBSource {
-	case DBSource_postgres, DBSource_mysql:
-		query, err := o.sql.PrepareNamed(ToNamedStatement(o.DBSource, stmt, names))
+	case DBSource_postgres, DBSource_mysql, DBSource_sqlite:
+		query, release, err := o.prepareNamed(ctx, stmt, names)
 		if err != nil {
 			return fmt.Errorf("prepare named: %w", err)
 		}
-		return query.Get(dst, args)
+		defer release()
+		return query.GetContext(ctx, dst, args)
 	case DBSource_cql:
-		if val, ok := args.(map[string]interface{}); ok {
-			return o.cql.Query(stmt, names).BindMap(val).Get(dst)
-		} else {
-			return o.cql.Query(stmt, names).BindStruct(args).Get(dst)
-		}
+		return o.cqlReadQuery(ctx, stmt, names, args).Get(dst)
 	default:
 		return ErrNoSourceConfigured
 	}
@@ -165,26 +237,34 @@ func (o *DB) Get(dst interface{}, stmt string, names []string, args interface{})
 // Deprecated
 func (o *DB) GetFromMap(dst interface{}, stmt string, names []string, args map[string]interface{}) error {
 	switch o.DBSource {
-	case DBSource_postgres, DBSource_mysql:
-		query, err := o.sql.PrepareNamed(ToNamedStatement(o.DBSource, stmt, names))
+	case DBSource_postgres, DBSource_mysql, DBSource_sqlite:
+		query, release, err := o.prepareNamed(context.Background(), stmt, names)
 		if err != nil {
 			return fmt.Errorf("prepare named: %w", err)
 		}
+		defer release()
 		return query.Get(dst, args)
 	case DBSource_cql:
 
-		return o.cql.Query(stmt, names).BindMap(args).Get(dst)
+		query := o.cql.Query(stmt, names).BindMap(args)
+		o.speculate(query.Query)
+		return query.Get(dst)
 	default:
 		return ErrNoSourceConfigured
 	}
 }
 
 func (o *DB) Ping() error {
+	return o.PingContext(context.Background())
+}
+
+// PingContext checks the connection, for cql it reads the version from system.local
+func (o *DB) PingContext(ctx context.Context) error {
 	if o.cql != nil {
-		return o.cql.ExecStmt("SELECT cql_version FROM system.local")
+		return o.cql.ContextQuery(ctx, "SELECT cql_version FROM system.local", nil).ExecRelease()
 	}
 	if o.sql != nil {
-		return o.sql.Ping()
+		return o.sql.PingContext(ctx)
 	}
 	return errors.New("no source configured")
 }
@@ -238,6 +318,11 @@ func (o *DB) DropAll() error {
 }
 
 func (o *DB) WriteBatch(queries []string, namesForSrcs [][]string, srcs []interface{}, opts ...BatchOption) error {
+	return o.WriteBatchContext(context.Background(), queries, namesForSrcs, srcs, opts...)
+}
+
+// WriteBatchContext writes the srcs in one cql batch, or in one sql transaction that is rolled back on the first error
+func (o *DB) WriteBatchContext(ctx context.Context, queries []string, namesForSrcs [][]string, srcs []interface{}, opts ...BatchOption) error {
 	bOpts := &BatchOptions{
 		BatchType: gocql.LoggedBatch,
 	}
@@ -252,7 +337,7 @@ func (o *DB) WriteBatch(queries []string, namesForSrcs [][]string, srcs []interf
 	}
 
 	if o.cql != nil {
-		batch := o.cql.Session.NewBatch(bOpts.BatchType)
+		batch := o.cql.Session.NewBatch(bOpts.BatchType).WithContext(ctx)
 		for i, query := range queries {
 			var args []interface{}
 			// Set Args
@@ -265,23 +350,29 @@ func (o *DB) WriteBatch(queries []string, namesForSrcs [][]string, srcs []interf
 		if err := o.cql.Session.ExecuteBatch(batch); err != nil {
 			return fmt.Errorf("execute batch: %v", err)
 		}
+		return nil
 	}
 
 	if o.sql != nil {
-		tx, err := o.sql.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelDefault})
+		tx, err := o.sql.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelDefault})
 		if err != nil {
 			return err
 		}
+		if o.DBSource == DBSource_mysql {
+			if _, err := tx.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS=0"); err != nil {
+				if err := tx.Rollback(); err != nil {
+					return fmt.Errorf("exec rollback: %v", err)
+				}
+				return fmt.Errorf("exec foriegn: %v", err)
+			}
+		}
 		for i, query := range queries {
 			var args []interface{}
 			for _, name := range namesForSrcs[i] {
 				args = append(args, o.sql.Mapper.FieldByName(reflect.ValueOf(srcs[i]), name).Interface())
 			}
-			if _, err := tx.Exec("SET FOREIGN_KEY_CHECKS=0"); err != nil {
-				return fmt.Errorf("exec foriegn: %v", err)
-			}
 			q := FromQueryBuilder(o.DBSource, query)
-			if _, err := tx.Exec(q, args...); err != nil {
+			if _, err := tx.ExecContext(ctx, q, args...); err != nil {
 				if err := tx.Rollback(); err != nil {
 					return fmt.Errorf("exec rollback: %v", err)
 				}
@@ -292,7 +383,7 @@ func (o *DB) WriteBatch(queries []string, namesForSrcs [][]string, srcs []interf
 		if err := tx.Commit(); err != nil {
 			return fmt.Errorf("commit transaction: %v", err)
 		}
-
+		return nil
 	}
 
 	return ErrNoSourceConfigured
@@ -311,12 +402,11 @@ func (in *BatchOptions) applyOption(bo *BatchOptions) error {
 	return nil
 }
 
+// ScannerIterator is the subset of Rows that only scans by position
 type ScannerIterator interface {
 	Next() bool
 	Err() error
 	Scanner
-	// TODO: check if close is needed?
-	// Close() error
 }
 
 type Scanner interface {
@@ -324,15 +414,30 @@ type Scanner interface {
 }
 
 func (o *DB) Query(stmt string, args ...interface{}) (ScannerIterator, error) {
+	return o.QueryContext(context.Background(), stmt, args...)
+}
+
+// QueryContext stops reading the rows when ctx is done, the iterator is the Rows of QueryRows
+func (o *DB) QueryContext(ctx context.Context, stmt string, args ...interface{}) (ScannerIterator, error) {
+	rows, err := o.QueryRows(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// QueryRows runs a statement with positional args, the returned Rows must be closed
+func (o *DB) QueryRows(ctx context.Context, stmt string, args ...interface{}) (Rows, error) {
 	if o.cql != nil {
-		query := o.cql.Session.Query(stmt, args...)
-		defer query.Release()
-		return query.Iter().Scanner(), query.Exec()
+		query := gocqlx.Query(o.cql.Session.Query(stmt, args...).WithContext(ctx), nil)
+		query.Mapper = o.cql.Mapper
+		o.speculate(query.Query)
+		return newCQLRows(query), nil
 	}
 	if o.sql != nil {
-		query, err := o.sql.DB.Query(stmt, args...)
+		query, err := o.sql.QueryxContext(ctx, stmt, args...)
 		if err != nil {
-			return nil, fmt.Errorf("sql query: %v", err)
+			return nil, fmt.Errorf("sql query: %w", err)
 		}
 		return query, nil
 	}
@@ -347,15 +452,21 @@ func (es emptyScanner) Scan(dest ...interface{}) error {
 }
 
 func (o *DB) QueryRow(stmt string, args ...interface{}) Scanner {
+	return o.QueryRowContext(context.Background(), stmt, args...)
+}
+
+// QueryRowContext returns the first row, an error of the query is returned by Scan
+func (o *DB) QueryRowContext(ctx context.Context, stmt string, args ...interface{}) Scanner {
 	if o.cql != nil {
-		query := o.cql.Session.Query(stmt, args...)
+		query := o.cql.Session.Query(stmt, args...).WithContext(ctx)
+		o.speculate(query)
 		query.Scan()
 		defer query.Release()
 		return query
 	}
 
 	if o.sql != nil {
-		row := o.sql.DB.QueryRow(stmt, args...)
+		row := o.sql.DB.QueryRowContext(ctx, stmt, args...)
 
 		return row
 	}
@@ -363,6 +474,7 @@ func (o *DB) QueryRow(stmt string, args ...interface{}) Scanner {
 	return emptyScanner(func() error { return ErrNoSourceConfigured })
 }
 
+// Deprecated: use Rows, IterWithErr can not tell the end of the rows apart from an error
 type IterWithErr struct {
 	iterx *gocqlx.Iterx
 	err   error
@@ -389,70 +501,148 @@ func (iter *IterWithErr) Scan(dest ...interface{}) error {
 }
 
 func (o *DB) Queryx(stmt string, names []string, args ...interface{}) (ScannerIterator, error) {
+	return o.QueryxContext(context.Background(), stmt, names, args...)
+}
+
+// QueryxContext stops reading the rows when ctx is done, the iterator is the Rows of QueryxRows
+func (o *DB) QueryxContext(ctx context.Context, stmt string, names []string, args ...interface{}) (ScannerIterator, error) {
+	rows, err := o.QueryxRows(ctx, stmt, names, args...)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// QueryxRows runs a statement with the positional args bound to names in order, the returned Rows must be closed.
+// Without names the statement and the args are passed to the driver as they are.
+func (o *DB) QueryxRows(ctx context.Context, stmt string, names []string, args ...interface{}) (Rows, error) {
 	if o.cql != nil {
-		query := o.cql.Query(stmt, names).Bind(args...)
-		if err := query.Exec(); err != nil {
-			return nil, fmt.Errorf("exec release: %v", err)
+		query := o.cql.ContextQuery(ctx, stmt, names).Bind(args...)
+		o.speculate(query.Query)
+		return newCQLRows(query), nil
+	}
+
+	if o.sql != nil {
+		if len(names) == 0 {
+			query, err := o.sql.QueryxContext(ctx, stmt, args...)
+			if err != nil {
+				return nil, fmt.Errorf("sql queryx: %w", err)
+			}
+			return query, nil
 		}
-		iter := query.Iter()
 
-		return &IterWithErr{iter, nil}, nil
+		namedStmt, err := CompileNamedStatement(o.DBSource, stmt, names)
+		if err != nil {
+			return nil, fmt.Errorf("named statement: %w", err)
+		}
+		if len(args) != len(names) {
+			return nil, fmt.Errorf("sql queryx: got %d args for %d names", len(args), len(names))
+		}
+		argMap := make(map[string]interface{}, len(names))
+		for i, name := range names {
+			argMap[name] = args[i]
+		}
+		query, err := o.sql.NamedQueryContext(ctx, namedStmt, argMap)
+		if err != nil {
+			return nil, fmt.Errorf("sql queryx: %w", err)
+		}
+		return query, nil
+	}
+
+	return nil, ErrNoSourceConfigured
+}
+
+// bindQuery runs a statement with args bound by name from a struct or a map, the returned Rows must be closed
+func (o *DB) bindQuery(ctx context.Context, stmt string, names []string, args interface{}) (Rows, error) {
+	if o.cql != nil {
+		query := o.cqlReadQuery(ctx, stmt, names, args)
+		if err := query.Err(); err != nil {
+			return nil, fmt.Errorf("cql bind: %w", err)
+		}
+		return newCQLRows(query), nil
 	}
 
 	if o.sql != nil {
-		named := ToNamedStatement(o.DBSource, stmt, names)
-		o.Debugf("name: %v", named)
-		query, err := o.sql.Queryx(named, args...)
+		namedStmt, err := CompileNamedStatement(o.DBSource, stmt, names)
 		if err != nil {
-			return nil, fmt.Errorf("sql queryx: %v", err)
+			return nil, fmt.Errorf("named statement: %w", err)
 		}
-		return query, nil
+		rows, err := o.sql.NamedQueryContext(ctx, namedStmt, args)
+		if err != nil {
+			return nil, fmt.Errorf("named query: %w", err)
+		}
+		return rows, nil
 	}
 
 	return nil, ErrNoSourceConfigured
 }
 
 func (o *DB) ExecStmt(stmt string) error {
+	return o.ExecStmtContext(context.Background(), stmt)
+}
+
+// ExecStmtContext runs a statement without args, ie. DDL
+func (o *DB) ExecStmtContext(ctx context.Context, stmt string) error {
 	if o.cql != nil {
-		return o.cql.ExecStmt(stmt)
+		return o.cql.ContextQuery(ctx, stmt, nil).ExecRelease()
 	}
 
 	if o.sql != nil {
-		_, err := o.sql.Exec(stmt)
+		_, err := o.sql.ExecContext(ctx, stmt)
 		return err
 	}
 	return ErrNoSourceConfigured
 }
 
 func (o *DB) Exec(stmt string, names []string, args interface{}) error {
+	return o.ExecContext(context.Background(), stmt, names, args)
+}
+
+// ExecContext binds the args from a struct or a map by names
+func (o *DB) ExecContext(ctx context.Context, stmt string, names []string, args interface{}) error {
 	if o.cql != nil {
-		query := o.cql.Query(stmt, names).BindStruct(args)
-		return query.ExecRelease()
+		return o.cqlQuery(ctx, stmt, names, args).ExecRelease()
 	}
 	if o.sql != nil {
-		namedStmt := ToNamedStatement(o.DBSource, stmt, names)
-		_, err := o.sql.NamedExec(namedStmt, args)
+		namedStmt, err := CompileNamedStatement(o.DBSource, stmt, names)
+		if err != nil {
+			return fmt.Errorf("named statement: %w", err)
+		}
+		_, err = o.sql.NamedExecContext(ctx, namedStmt, args)
 		return err
 	}
 	return errors.New("no source configured")
 }
 
 func (o *DB) ExecMap(stmt string, names []string, args map[string]interface{}) error {
+	return o.ExecMapContext(context.Background(), stmt, names, args)
+}
+
+// ExecMapContext binds the args from a map by names
+func (o *DB) ExecMapContext(ctx context.Context, stmt string, names []string, args map[string]interface{}) error {
 	if o.cql != nil {
-		query := o.cql.Query(stmt, names).BindMap(args)
+		query := o.cql.ContextQuery(ctx, stmt, names).BindMap(args)
 		return query.ExecRelease()
 	}
 	if o.sql != nil {
-		namedStmt := ToNamedStatement(o.DBSource, stmt, names)
-		_, err := o.sql.NamedExec(namedStmt, args)
+		namedStmt, err := CompileNamedStatement(o.DBSource, stmt, names)
+		if err != nil {
+			return fmt.Errorf("named statement: %w", err)
+		}
+		_, err = o.sql.NamedExecContext(ctx, namedStmt, args)
 		return err
 	}
 	return errors.New("no source configured")
 }
 
 func (o *DB) ExecMany(stmt string, names []string, args ...interface{}) error {
+	return o.ExecManyContext(context.Background(), stmt, names, args...)
+}
+
+// ExecManyContext runs the statement once per arg, the sql statement is prepared once
+func (o *DB) ExecManyContext(ctx context.Context, stmt string, names []string, args ...interface{}) error {
 	if o.cql != nil {
-		query := o.cql.Query(stmt, names)
+		query := o.cql.ContextQuery(ctx, stmt, names)
 		defer query.Release()
 		for _, arg := range args {
 			query = query.Bind(arg)
@@ -463,28 +653,40 @@ func (o *DB) ExecMany(stmt string, names []string, args ...interface{}) error {
 		return nil
 	}
 	if o.sql != nil {
-		query, err := o.sql.PrepareNamed(ToNamedStatement(o.DBSource, stmt, names))
+		query, release, err := o.prepareNamed(ctx, stmt, names)
 		if err != nil {
 			return err
 		}
+		defer release()
 
 		for _, arg := range args {
-			_, err := query.Exec(arg)
+			_, err := query.ExecContext(ctx, arg)
 			if err != nil {
 				return fmt.Errorf("sql: %w", err)
 			}
 		}
-		return query.Close()
+		return nil
 	}
 	return ErrNoSourceConfigured
 }
 
+// Close releases the connection, it is closed once every DB sharing it has been closed
 func (o *DB) Close() error {
+	if o.registry != nil && o.registryKey != "" {
+		return o.registry.release(o)
+	}
+	return o.closeConnection()
+}
+
+func (o *DB) closeConnection() error {
 	if o.cql != nil {
 		o.cql.Close()
 		return nil
 	}
 	if o.sql != nil {
+		if o.stmts != nil {
+			o.stmts.close()
+		}
 		return o.sql.Close()
 	}
 	return ErrNoSourceConfigured
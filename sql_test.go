@@ -191,14 +191,19 @@ func TestNew(t *testing.T) {
 				},
 			},
 			want: &DB{
-				DBSource:    DBSource_mysql,
-				User:        "mysql",
-				Password:    "mysql",
-				Port:        "3306",
-				DBName:      "test_db",
-				Hosts:       []string{"127.0.0.1"},
-				Migrate:     true,
-				MigratePath: "database/mysql",
+				DBSource:           DBSource_mysql,
+				User:               "mysql",
+				Password:           "mysql",
+				Port:               "3306",
+				DBName:             "test_db",
+				Hosts:              []string{"127.0.0.1"},
+				Migrate:            true,
+				MigratePath:        "database/mysql",
+				TxRetries:          defaultTxRetries,
+				StmtCacheSize:      defaultStmtCacheSize,
+				MigrateLockTTL:     defaultMigrateLockTTL,
+				MigrateLockTimeout: defaultMigrateLockTimeout,
+				Pool:               defaultPoolConfig,
 			},
 		},
 		{
@@ -214,13 +219,18 @@ func TestNew(t *testing.T) {
 				},
 			},
 			want: &DB{
-				DBSource:    DBSource_mysql,
-				User:        "mysql",
-				Password:    "mysql",
-				Port:        "3306",
-				DBName:      "test_db",
-				Hosts:       []string{"127.0.0.1"},
-				MigratePath: "database/sql",
+				DBSource:           DBSource_mysql,
+				User:               "mysql",
+				Password:           "mysql",
+				Port:               "3306",
+				DBName:             "test_db",
+				Hosts:              []string{"127.0.0.1"},
+				MigratePath:        "database/sql",
+				TxRetries:          defaultTxRetries,
+				StmtCacheSize:      defaultStmtCacheSize,
+				MigrateLockTTL:     defaultMigrateLockTTL,
+				MigrateLockTimeout: defaultMigrateLockTimeout,
+				Pool:               defaultPoolConfig,
 			},
 		},
 	}
@@ -232,8 +242,7 @@ func TestNew(t *testing.T) {
 				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			src, _ := got.getDataSource()
-			deleteDB(src)
+			defer got.Close()
 
 			defer func() {
 				if err := got.DropTables("schema_migrations"); err != nil {
@@ -244,7 +253,7 @@ func TestNew(t *testing.T) {
 				// }
 			}()
 
-			opts := []cmp.Option{cmpopts.IgnoreUnexported(DB{}), cmpopts.IgnoreFields(DB{}, "sql", "cql")}
+			opts := []cmp.Option{cmpopts.IgnoreUnexported(DB{}), cmpopts.IgnoreFields(DB{}, "sql", "cql", "PageTokenKey")}
 			if !cmp.Equal(got, tt.want, opts...) {
 				t.Error(cmp.Diff(got, tt.want, opts...))
 			}
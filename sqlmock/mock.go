@@ -0,0 +1,419 @@
+// Package sqlmock is a sql.Querier for unit tests. The statements a test expects are registered
+// with their canned results or errors, and the calls are checked against them.
+//
+//	mock := sqlmock.New()
+//	mock.ExpectSelect("SELECT id, name FROM users WHERE id = ?", "id").
+//		WithArgs(User{ID: 1}).
+//		WillReturnRows(sqlmock.NewRows("id", "name").AddRow(1, "joe"))
+//
+//	users, err := NewService(mock).Users(User{ID: 1})
+//	...
+//	if err := mock.ExpectationsWereMet(); err != nil {
+//		t.Error(err)
+//	}
+//
+// Statements are matched after sql.ToNamedStatement, so the placeholder style and the whitespace don't matter,
+// only the names bound to the placeholders.
+package sqlmock
+
+import (
+	stdsql "database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx/reflectx"
+	sql "github.com/joematpal/go-sql/v2"
+	"github.com/joematpal/go-sql/v2/table"
+	cqlreflectx "github.com/scylladb/go-reflectx"
+)
+
+// ErrUnexpectedCall is returned when a call matches no expectation
+var ErrUnexpectedCall = errors.New("sqlmock: unexpected call")
+
+var _ sql.Querier = (*Mock)(nil)
+
+// Method is the Querier method an expectation is for
+type Method string
+
+const (
+	MethodSelect     Method = "Select"
+	MethodGet        Method = "Get"
+	MethodExec       Method = "Exec"
+	MethodExecMap    Method = "ExecMap"
+	MethodExecMany   Method = "ExecMany"
+	MethodQueryx     Method = "Queryx"
+	MethodQueryRow   Method = "QueryRow"
+	MethodWriteBatch Method = "WriteBatch"
+	MethodPing       Method = "Ping"
+)
+
+// Mock is a sql.Querier that answers with the expectations registered on it, it is safe for concurrent use
+type Mock struct {
+	mu           sync.Mutex
+	dbSource     sql.DBSource
+	ordered      bool
+	mapFunc      func(string) string
+	tagMapFunc   func(string) string
+	mapper       *reflectx.Mapper
+	expectations []*Expectation
+}
+
+// Option interface to change how the Mock matches its calls
+type Option interface {
+	applyOption(*Mock)
+}
+
+type optionApplyFunc func(*Mock)
+
+func (f optionApplyFunc) applyOption(m *Mock) {
+	f(m)
+}
+
+// WithDBSource sets the placeholder style of the statements, it defaults to postgres which accepts $n and ?
+func WithDBSource(dbSource sql.DBSource) Option {
+	return optionApplyFunc(func(m *Mock) {
+		m.dbSource = dbSource
+	})
+}
+
+// WithUnordered lets the calls match the expectations in any order, by default they must be called in order
+func WithUnordered() Option {
+	return optionApplyFunc(func(m *Mock) {
+		m.ordered = false
+	})
+}
+
+// WithMapFunc maps the names of the fields without a tag to columns, the same as sql.WithMapFunc
+func WithMapFunc(mapFunc func(string) string) Option {
+	return optionApplyFunc(func(m *Mock) {
+		m.mapFunc = mapFunc
+	})
+}
+
+// WithTagMapFunc maps the names in the json tags to columns, the same as sql.WithTagMapFunc
+func WithTagMapFunc(tagMapFunc func(string) string) Option {
+	return optionApplyFunc(func(m *Mock) {
+		m.tagMapFunc = tagMapFunc
+	})
+}
+
+// New returns a Mock without expectations
+func New(opts ...Option) *Mock {
+	m := &Mock{
+		dbSource:   sql.DBSource_postgres,
+		ordered:    true,
+		mapFunc:    cqlreflectx.CamelToSnakeASCII,
+		tagMapFunc: cqlreflectx.CamelToSnakeASCII,
+	}
+	for _, opt := range opts {
+		opt.applyOption(m)
+	}
+	// The rows are scanned into structs the same way as sql.DB does
+	m.mapper = reflectx.NewMapperTagFunc("json", table.PreMapFunc(m.mapFunc), table.PreMapFunc(m.tagMapFunc))
+	return m
+}
+
+// Expectation is a call the test expects, by default it is met once and returns no error
+type Expectation struct {
+	method  Method
+	stmts   []string
+	hasArgs bool
+	args    interface{}
+	value   interface{}
+	rows    *Rows
+	err     error
+	times   int
+	calls   int
+}
+
+// WithArgs sets the args the call must get, compared with reflect.DeepEqual.
+// For ExecMany, Queryx and QueryRow they are the variadic args, for WriteBatch the srcs.
+func (e *Expectation) WithArgs(args interface{}) *Expectation {
+	e.hasArgs, e.args = true, args
+	return e
+}
+
+// WillReturn sets the value that Select or Get assign to dst
+func (e *Expectation) WillReturn(value interface{}) *Expectation {
+	e.value = value
+	return e
+}
+
+// WillReturnRows sets the rows of Queryx and QueryRow, Select and Get scan them into dst
+func (e *Expectation) WillReturnRows(rows *Rows) *Expectation {
+	e.rows = rows
+	return e
+}
+
+// WillReturnError makes the call fail with err
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Times sets how many calls the expectation is met by, n < 1 is any number of calls including none
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+func (e *Expectation) done() bool {
+	return e.times > 0 && e.calls >= e.times
+}
+
+func (e *Expectation) met() bool {
+	return e.times < 1 || e.calls >= e.times
+}
+
+func (e *Expectation) String() string {
+	if len(e.stmts) == 0 {
+		return string(e.method)
+	}
+	return fmt.Sprintf("%s %q", e.method, strings.Join(e.stmts, "; "))
+}
+
+func (m *Mock) expect(method Method, stmts ...string) *Expectation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := &Expectation{method: method, stmts: stmts, times: 1}
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// ExpectSelect expects a Select of stmt with the placeholders bound to names
+func (m *Mock) ExpectSelect(stmt string, names ...string) *Expectation {
+	return m.expect(MethodSelect, m.normalize(stmt, names))
+}
+
+// ExpectGet expects a Get of stmt with the placeholders bound to names
+func (m *Mock) ExpectGet(stmt string, names ...string) *Expectation {
+	return m.expect(MethodGet, m.normalize(stmt, names))
+}
+
+// ExpectExec expects an Exec of stmt with the placeholders bound to names
+func (m *Mock) ExpectExec(stmt string, names ...string) *Expectation {
+	return m.expect(MethodExec, m.normalize(stmt, names))
+}
+
+// ExpectExecMap expects an ExecMap of stmt with the placeholders bound to names
+func (m *Mock) ExpectExecMap(stmt string, names ...string) *Expectation {
+	return m.expect(MethodExecMap, m.normalize(stmt, names))
+}
+
+// ExpectExecMany expects an ExecMany of stmt with the placeholders bound to names
+func (m *Mock) ExpectExecMany(stmt string, names ...string) *Expectation {
+	return m.expect(MethodExecMany, m.normalize(stmt, names))
+}
+
+// ExpectQueryx expects a Queryx of stmt with the placeholders bound to names
+func (m *Mock) ExpectQueryx(stmt string, names ...string) *Expectation {
+	return m.expect(MethodQueryx, m.normalize(stmt, names))
+}
+
+// ExpectQueryRow expects a QueryRow of stmt, its placeholders are positional
+func (m *Mock) ExpectQueryRow(stmt string) *Expectation {
+	return m.expect(MethodQueryRow, m.normalize(stmt, nil))
+}
+
+// ExpectWriteBatch expects a WriteBatch of the queries with the placeholders bound to namesForSrcs
+func (m *Mock) ExpectWriteBatch(queries []string, namesForSrcs [][]string) *Expectation {
+	return m.expect(MethodWriteBatch, m.normalizeBatch(queries, namesForSrcs)...)
+}
+
+// ExpectPing expects a Ping
+func (m *Mock) ExpectPing() *Expectation {
+	return m.expect(MethodPing)
+}
+
+// ExpectationsWereMet returns an error listing the expectations that were not called as often as expected
+func (m *Mock) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var unmet []string
+	for _, e := range m.expectations {
+		if !e.met() {
+			unmet = append(unmet, fmt.Sprintf("%s called %d of %d times", e, e.calls, e.times))
+		}
+	}
+	if len(unmet) > 0 {
+		return fmt.Errorf("sqlmock: unmet expectations:\n\t%s", strings.Join(unmet, "\n\t"))
+	}
+	return nil
+}
+
+// normalize converts the placeholders to names and collapses the whitespace
+func (m *Mock) normalize(stmt string, names []string) string {
+	return strings.Join(strings.Fields(sql.ToNamedStatement(m.dbSource, stmt, names)), " ")
+}
+
+func (m *Mock) normalizeBatch(queries []string, namesForSrcs [][]string) []string {
+	out := make([]string, len(queries))
+	for i, q := range queries {
+		var names []string
+		if i < len(namesForSrcs) {
+			names = namesForSrcs[i]
+		}
+		out[i] = m.normalize(q, names)
+	}
+	return out
+}
+
+// match finds the expectation of the call and counts the call
+func (m *Mock) match(method Method, stmts []string, args interface{}) (*Expectation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	call := &Expectation{method: method, stmts: stmts}
+	for _, e := range m.expectations {
+		if e.done() {
+			continue
+		}
+		if e.method == method && reflect.DeepEqual(e.stmts, stmts) && (!e.hasArgs || reflect.DeepEqual(e.args, args)) {
+			e.calls++
+			return e, nil
+		}
+		if m.ordered && !e.met() {
+			return nil, fmt.Errorf("%w: %s with args %#v, next expectation is %s", ErrUnexpectedCall, call, args, e)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s with args %#v", ErrUnexpectedCall, call, args)
+}
+
+func (m *Mock) Select(dst interface{}, stmt string, names []string, args interface{}) error {
+	e, err := m.match(MethodSelect, []string{m.normalize(stmt, names)}, args)
+	if err != nil {
+		return err
+	}
+	return e.scan(dst, true, m.mapper)
+}
+
+func (m *Mock) Get(dst interface{}, stmt string, names []string, args interface{}) error {
+	e, err := m.match(MethodGet, []string{m.normalize(stmt, names)}, args)
+	if err != nil {
+		return err
+	}
+	return e.scan(dst, false, m.mapper)
+}
+
+// scan assigns the value or the rows of the expectation to dst
+func (e *Expectation) scan(dst interface{}, all bool, mapper *reflectx.Mapper) error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.value != nil {
+		return assign(dst, e.value)
+	}
+	if e.rows == nil {
+		if all {
+			return nil
+		}
+		return stdsql.ErrNoRows
+	}
+
+	it := e.rows.iter(mapper)
+	if all {
+		return it.scanAll(dst)
+	}
+	if !it.Next() {
+		if err := it.Err(); err != nil {
+			return err
+		}
+		return stdsql.ErrNoRows
+	}
+	return it.scanOne(dst)
+}
+
+func (m *Mock) Exec(stmt string, names []string, args interface{}) error {
+	e, err := m.match(MethodExec, []string{m.normalize(stmt, names)}, args)
+	if err != nil {
+		return err
+	}
+	return e.err
+}
+
+func (m *Mock) ExecMap(stmt string, names []string, args map[string]interface{}) error {
+	e, err := m.match(MethodExecMap, []string{m.normalize(stmt, names)}, args)
+	if err != nil {
+		return err
+	}
+	return e.err
+}
+
+func (m *Mock) ExecMany(stmt string, names []string, args ...interface{}) error {
+	e, err := m.match(MethodExecMany, []string{m.normalize(stmt, names)}, args)
+	if err != nil {
+		return err
+	}
+	return e.err
+}
+
+// Queryx returns an iterator that is a sql.Rows like the one of sql.DB
+func (m *Mock) Queryx(stmt string, names []string, args ...interface{}) (sql.ScannerIterator, error) {
+	e, err := m.match(MethodQueryx, []string{m.normalize(stmt, names)}, args)
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	if e.rows == nil {
+		return NewRows().iter(m.mapper), nil
+	}
+	return e.rows.iter(m.mapper), nil
+}
+
+func (m *Mock) QueryRow(stmt string, args ...interface{}) sql.Scanner {
+	e, err := m.match(MethodQueryRow, []string{m.normalize(stmt, nil)}, args)
+	if err != nil {
+		return errScanner{err}
+	}
+	if e.err != nil {
+		return errScanner{e.err}
+	}
+	if e.rows == nil {
+		return errScanner{stdsql.ErrNoRows}
+	}
+	return rowScanner{e.rows.iter(m.mapper)}
+}
+
+func (m *Mock) WriteBatch(queries []string, namesForSrcs [][]string, srcs []interface{}, opts ...sql.BatchOption) error {
+	e, err := m.match(MethodWriteBatch, m.normalizeBatch(queries, namesForSrcs), srcs)
+	if err != nil {
+		return err
+	}
+	return e.err
+}
+
+func (m *Mock) Ping() error {
+	e, err := m.match(MethodPing, nil, nil)
+	if err != nil {
+		return err
+	}
+	return e.err
+}
+
+type errScanner struct {
+	err error
+}
+
+func (s errScanner) Scan(dest ...interface{}) error {
+	return s.err
+}
+
+// rowScanner scans the first row like sql.Row
+type rowScanner struct {
+	it *rowsIter
+}
+
+func (s rowScanner) Scan(dest ...interface{}) error {
+	if !s.it.Next() {
+		if err := s.it.Err(); err != nil {
+			return err
+		}
+		return stdsql.ErrNoRows
+	}
+	return s.it.Scan(dest...)
+}
@@ -0,0 +1,199 @@
+package sqlmock
+
+import (
+	stdsql "database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	sql "github.com/joematpal/go-sql/v2"
+)
+
+type user struct {
+	ID        int    `json:"id"`
+	FirstName string `json:"firstName"`
+}
+
+func TestMock(t *testing.T) {
+	errWrite := errors.New("write failed")
+	mock := New()
+	mock.ExpectPing()
+	mock.ExpectSelect("SELECT id, first_name FROM users WHERE id > $1", "id").
+		WithArgs(map[string]interface{}{"id": 0}).
+		WillReturnRows(NewRows("id", "first_name").AddRow(1, "joe").AddRow(int64(2), "jane"))
+	mock.ExpectGet("SELECT count(*) FROM users").WillReturn(2)
+	mock.ExpectExec("INSERT INTO users (id, first_name) VALUES (?, ?)", "id", "firstName").WillReturnError(errWrite)
+	mock.ExpectQueryRow("SELECT first_name FROM users WHERE id = ?").
+		WithArgs([]interface{}{1}).
+		WillReturnRows(NewRows("first_name").AddRow("joe"))
+
+	var q sql.Querier = mock
+	if err := q.Ping(); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	var users []user
+	// The placeholder style and whitespace differ from the expectation
+	if err := q.Select(&users, "SELECT id, first_name\n\tFROM users WHERE id > ?", []string{"id"}, map[string]interface{}{"id": 0}); err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if diff := cmp.Diff([]user{{1, "joe"}, {2, "jane"}}, users); diff != "" {
+		t.Errorf("Select() mismatch (-want +got):\n%s", diff)
+	}
+
+	var count int
+	if err := q.Get(&count, "SELECT count(*) FROM users", nil, nil); err != nil || count != 2 {
+		t.Errorf("Get() = %v, %v, want 2, nil", count, err)
+	}
+
+	if err := q.Exec("INSERT INTO users (id, first_name) VALUES ($1, $2)", []string{"id", "firstName"}, user{}); !errors.Is(err, errWrite) {
+		t.Errorf("Exec() error = %v, want %v", err, errWrite)
+	}
+
+	var name string
+	if err := q.QueryRow("SELECT first_name FROM users WHERE id = ?", 1).Scan(&name); err != nil || name != "joe" {
+		t.Errorf("QueryRow().Scan() = %v, %v, want joe, nil", name, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMock_unexpected(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		call    func(m *Mock) error
+		wantErr error
+		wantMet bool
+	}{
+		{
+			name:    "should fail; different names",
+			call:    func(m *Mock) error { return m.Exec("DELETE FROM users WHERE id = ?", []string{"userId"}, nil) },
+			wantErr: ErrUnexpectedCall,
+		},
+		{
+			name:    "should fail; different args",
+			call:    func(m *Mock) error { return m.Exec("DELETE FROM users WHERE id = ?", []string{"id"}, 2) },
+			wantErr: ErrUnexpectedCall,
+		},
+		{
+			name:    "should fail; out of order",
+			call:    func(m *Mock) error { return m.Ping() },
+			wantErr: ErrUnexpectedCall,
+		},
+		{
+			name: "should pass; unordered",
+			opts: []Option{WithUnordered()},
+			call: func(m *Mock) error { return m.Ping() },
+		},
+		{
+			name:    "should pass; in order",
+			call:    func(m *Mock) error { return m.Exec("DELETE FROM users WHERE id = ?", []string{"id"}, 1) },
+			wantMet: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(tt.opts...)
+			m.ExpectExec("DELETE FROM users WHERE id = ?", "id").WithArgs(1)
+			m.ExpectPing()
+
+			if err := tt.call(m); !errors.Is(err, tt.wantErr) {
+				t.Errorf("call error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := m.ExpectationsWereMet(); (err == nil) != tt.wantMet {
+				t.Errorf("Mock.ExpectationsWereMet() error = %v, wantMet %v", err, tt.wantMet)
+			}
+		})
+	}
+}
+
+func TestMock_Queryx(t *testing.T) {
+	mock := New(WithDBSource(sql.DBSource_cql))
+	mock.ExpectQueryx("SELECT id, first_name FROM users WHERE id IN ?", "ids").
+		Times(2).
+		WillReturnRows(NewRows("id", "first_name").AddRow(1, "joe"))
+	mock.ExpectGet("SELECT first_name FROM users WHERE id = ?", "id").WillReturnRows(NewRows("first_name"))
+
+	for i := 0; i < 2; i++ {
+		iter, err := mock.Queryx("SELECT id, first_name FROM users WHERE id IN ?", []string{"ids"}, []int{1})
+		if err != nil {
+			t.Fatalf("Queryx() error = %v", err)
+		}
+		rows := iter.(sql.Rows)
+		var got []map[string]interface{}
+		for rows.Next() {
+			m := map[string]interface{}{}
+			if err := rows.MapScan(m); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, m)
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]map[string]interface{}{{"id": 1, "first_name": "joe"}}, got); diff != "" {
+			t.Errorf("Queryx() mismatch (-want +got):\n%s", diff)
+		}
+	}
+
+	var name string
+	if err := mock.Get(&name, "SELECT first_name FROM users WHERE id = ?", []string{"id"}, nil); !errors.Is(err, stdsql.ErrNoRows) {
+		t.Errorf("Get() error = %v, want %v", err, stdsql.ErrNoRows)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMock_WithTagMapFunc(t *testing.T) {
+	identity := func(s string) string { return s }
+	mock := New(WithTagMapFunc(identity))
+	mock.ExpectSelect("SELECT id, firstName FROM users").
+		WillReturnRows(NewRows("id", "firstName").AddRow(1, "joe"))
+
+	var users []user
+	if err := mock.Select(&users, "SELECT id, firstName FROM users", nil, nil); err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if diff := cmp.Diff([]user{{1, "joe"}}, users); diff != "" {
+		t.Errorf("Select() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_assign(t *testing.T) {
+	tests := []struct {
+		name    string
+		dst     interface{}
+		v       interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "should pass; int64 to int", dst: new(int), v: int64(2), want: 2},
+		{name: "should pass; whole float to int", dst: new(int), v: 2.0, want: 2},
+		{name: "should pass; int to float", dst: new(float64), v: 2, want: 2.0},
+		{name: "should pass; bytes to string", dst: new(string), v: []byte("joe"), want: "joe"},
+		{name: "should pass; string to bytes", dst: new([]byte), v: "joe", want: []byte("joe")},
+		{name: "should fail; int to string", dst: new(string), v: 65, wantErr: true},
+		{name: "should fail; float to int truncates", dst: new(int), v: 1.5, wantErr: true},
+		{name: "should fail; int overflows int8", dst: new(int8), v: 300, wantErr: true},
+		{name: "should fail; string to int", dst: new(int), v: "1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assign(tt.dst, tt.v)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("assign() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, reflect.ValueOf(tt.dst).Elem().Interface()); diff != "" {
+				t.Errorf("assign() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package sqlmock
+
+import (
+	stdsql "database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jmoiron/sqlx/reflectx"
+	sql "github.com/joematpal/go-sql/v2"
+)
+
+// Rows are the canned rows of an expectation
+type Rows struct {
+	columns []string
+	values  [][]interface{}
+	// err is returned by Err after the rows
+	err error
+}
+
+// NewRows returns rows with the columns, add the values with AddRow
+func NewRows(columns ...string) *Rows {
+	return &Rows{columns: columns}
+}
+
+// AddRow adds a row, there must be a value for every column
+func (r *Rows) AddRow(values ...interface{}) *Rows {
+	if len(values) != len(r.columns) {
+		panic(fmt.Sprintf("sqlmock: AddRow got %d values for %d columns", len(values), len(r.columns)))
+	}
+	r.values = append(r.values, values)
+	return r
+}
+
+// RowError makes Err return err once the rows have been read
+func (r *Rows) RowError(err error) *Rows {
+	r.err = err
+	return r
+}
+
+func (r *Rows) iter(mapper *reflectx.Mapper) *rowsIter {
+	return &rowsIter{rows: r, mapper: mapper, i: -1}
+}
+
+var _ sql.Rows = (*rowsIter)(nil)
+
+// rowsIter is a read of Rows, an expectation can return the same Rows more than once
+type rowsIter struct {
+	rows   *Rows
+	mapper *reflectx.Mapper
+	i      int
+	closed bool
+}
+
+func (it *rowsIter) Next() bool {
+	if it.closed || it.i+1 >= len(it.rows.values) {
+		it.closed = true
+		return false
+	}
+	it.i++
+	return true
+}
+
+func (it *rowsIter) current() ([]interface{}, error) {
+	if it.i < 0 || it.i >= len(it.rows.values) {
+		return nil, errors.New("sqlmock: scan called without a row, call Next first")
+	}
+	return it.rows.values[it.i], nil
+}
+
+func (it *rowsIter) Scan(dest ...interface{}) error {
+	row, err := it.current()
+	if err != nil {
+		return err
+	}
+	if len(dest) != len(row) {
+		return fmt.Errorf("sqlmock: scan got %d destinations for %d columns", len(dest), len(row))
+	}
+	for i, d := range dest {
+		if err := assign(d, row[i]); err != nil {
+			return fmt.Errorf("sqlmock: column %s: %w", it.rows.columns[i], err)
+		}
+	}
+	return nil
+}
+
+func (it *rowsIter) StructScan(dest interface{}) error {
+	row, err := it.current()
+	if err != nil {
+		return err
+	}
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("sqlmock: struct scan needs a non nil pointer")
+	}
+	v = reflect.Indirect(v)
+	fields := it.mapper.TraversalsByName(v.Type(), it.rows.columns)
+	for i, index := range fields {
+		if len(index) == 0 {
+			return fmt.Errorf("sqlmock: missing destination name %s in %T", it.rows.columns[i], dest)
+		}
+		f := reflectx.FieldByIndexes(v, index)
+		if err := assign(f.Addr().Interface(), row[i]); err != nil {
+			return fmt.Errorf("sqlmock: column %s: %w", it.rows.columns[i], err)
+		}
+	}
+	return nil
+}
+
+func (it *rowsIter) MapScan(dest map[string]interface{}) error {
+	row, err := it.current()
+	if err != nil {
+		return err
+	}
+	for i, c := range it.rows.columns {
+		dest[c] = row[i]
+	}
+	return nil
+}
+
+func (it *rowsIter) Columns() ([]string, error) {
+	return it.rows.columns, nil
+}
+
+func (it *rowsIter) Err() error {
+	if it.closed && it.i+1 >= len(it.rows.values) {
+		return it.rows.err
+	}
+	return nil
+}
+
+func (it *rowsIter) Close() error {
+	it.closed = true
+	return nil
+}
+
+// scanAll scans the rows into dst, a pointer to a slice of structs or of scalars
+func (it *rowsIter) scanAll(dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("sqlmock: select needs a pointer to a slice, got %T", dst)
+	}
+	slice := v.Elem()
+	for it.Next() {
+		elem := reflect.New(slice.Type().Elem())
+		if err := it.scanOne(elem.Interface()); err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, elem.Elem()))
+	}
+	return it.Err()
+}
+
+// scanOne scans the current row into dst, structs are scanned by column name
+func (it *rowsIter) scanOne(dst interface{}) error {
+	t := reflect.TypeOf(dst).Elem()
+	if t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{}) && !reflect.PtrTo(t).Implements(scannerType) {
+		return it.StructScan(dst)
+	}
+	return it.Scan(dst)
+}
+
+var scannerType = reflect.TypeOf((*stdsql.Scanner)(nil)).Elem()
+
+// assign sets the value of the pointer dst to v. Like database/sql it only converts between numbers,
+// when the value fits the destination, and between strings and bytes.
+func assign(dst interface{}, v interface{}) error {
+	if s, ok := dst.(stdsql.Scanner); ok {
+		return s.Scan(v)
+	}
+	d := reflect.ValueOf(dst)
+	if d.Kind() != reflect.Ptr || d.IsNil() {
+		return fmt.Errorf("destination %T is not a non nil pointer", dst)
+	}
+	d = d.Elem()
+	if v == nil {
+		d.Set(reflect.Zero(d.Type()))
+		return nil
+	}
+	src := reflect.ValueOf(v)
+	switch {
+	case src.Type().AssignableTo(d.Type()):
+		d.Set(src)
+	case isNumber(src.Kind()) && isNumber(d.Kind()):
+		converted := src.Convert(d.Type())
+		if converted.Convert(src.Type()).Interface() != src.Interface() {
+			return fmt.Errorf("%v doesn't fit in %s", v, d.Type())
+		}
+		d.Set(converted)
+	case isText(src.Type()) && isText(d.Type()):
+		d.Set(src.Convert(d.Type()))
+	default:
+		return fmt.Errorf("can't assign %T to %s", v, d.Type())
+	}
+	return nil
+}
+
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isText reports if t is a string or a []byte
+func isText(t reflect.Type) bool {
+	return t.Kind() == reflect.String || (t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8)
+}
@@ -0,0 +1,32 @@
+//go:build sqlite
+// +build sqlite
+
+package sqltest
+
+import (
+	"testing"
+	"testing/fstest"
+
+	sql "github.com/joematpal/go-sql/v2"
+)
+
+func TestNew_sqlite(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/01_users.up.sql": {Data: []byte("CREATE TABLE users (id int PRIMARY KEY)")},
+	}
+	opts := []sql.Option{
+		sql.WithDBSource("sqlite"),
+		sql.WithMigrateFS(fsys),
+		sql.WithMigratePath("migrations"),
+	}
+
+	for _, name := range []string{"first", "second"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			db := New(t, opts...)
+			if err := db.ExecStmt("INSERT INTO users (id) VALUES (1)"); err != nil {
+				t.Fatalf("insert into the migrated table: %v", err)
+			}
+		})
+	}
+}
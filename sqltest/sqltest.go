@@ -0,0 +1,133 @@
+// Package sqltest gives each test a database of its own so tests can run in parallel.
+//
+//	func TestUsers(t *testing.T) {
+//		t.Parallel()
+//		db := sqltest.New(t,
+//			sql.WithDBSource("postgres"),
+//			sql.WithHost("127.0.0.1"),
+//			sql.WithPort("5432"),
+//			sql.WithUser("postgres"),
+//			sql.WithPassword("postgres"),
+//			sql.WithDBName("postgres"),
+//			sql.WithMigratePath("../database/psql"),
+//		)
+//		...
+//	}
+package sqltest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sql "github.com/joematpal/go-sql/v2"
+)
+
+// maxNameLen fits the shortest limit, cql keyspaces are at most 48 characters
+const maxNameLen = 48
+
+// cqlAdminKeyspace always exists, the keyspace of the options may not
+const cqlAdminKeyspace = "system"
+
+// New creates a uniquely named postgres or mysql database, cql keyspace or sqlite file, connects to it
+// and runs the migrations of the options into it. The database is dropped when the test finishes.
+//
+// For postgres, mysql and cql the options connect to an existing database that the new one is created from,
+// cql always connects to the system keyspace. The migrations are enabled unless the options disable them.
+func New(t testing.TB, opts ...sql.Option) *sql.DB {
+	t.Helper()
+
+	cfg, err := sql.NewConfig(opts...)
+	if err != nil {
+		t.Fatalf("sqltest: config: %v", err)
+	}
+	name, err := Name(t)
+	if err != nil {
+		t.Fatalf("sqltest: name: %v", err)
+	}
+
+	dbName := name
+	if cfg.DBSource == sql.DBSource_sqlite {
+		dbName = filepath.Join(t.TempDir(), name+".db")
+	} else {
+		drop, err := create(cfg.DBSource, name, opts)
+		if err != nil {
+			t.Fatalf("sqltest: create %s: %v", name, err)
+		}
+		t.Cleanup(func() {
+			if err := drop(); err != nil {
+				t.Errorf("sqltest: drop %s: %v", name, err)
+			}
+		})
+	}
+
+	db, err := sql.New(append(append([]sql.Option{sql.WithMigrate(true)}, opts...), sql.WithDBName(dbName))...)
+	if db != nil {
+		// Cleanups run last in first out, the connection is closed before the database is dropped
+		t.Cleanup(func() {
+			if err := db.Close(); err != nil && err != sql.ErrNoSourceConfigured {
+				t.Errorf("sqltest: close %s: %v", name, err)
+			}
+		})
+	}
+	if err != nil {
+		t.Fatalf("sqltest: new %s: %v", name, err)
+	}
+	return db
+}
+
+// Name returns a unique name for a database of the test, made of the name of the test and a random suffix
+func Name(t testing.TB) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	suffix := "_" + hex.EncodeToString(b)
+
+	var sb strings.Builder
+	sb.WriteString("test_")
+	for _, r := range strings.ToLower(t.Name()) {
+		if sb.Len() >= maxNameLen-len(suffix) {
+			break
+		}
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	sb.WriteString(suffix)
+	return sb.String(), nil
+}
+
+// create creates the database or keyspace with an admin connection and returns the func that drops it
+func create(source sql.DBSource, name string, opts []sql.Option) (func() error, error) {
+	adminOpts := append(append([]sql.Option{}, opts...), sql.WithMigrate(false))
+	if source == sql.DBSource_cql {
+		adminOpts = append(adminOpts, sql.WithDBName(cqlAdminKeyspace))
+	}
+	admin, err := sql.New(adminOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("admin: %w", err)
+	}
+
+	quoted := source.Dialect().Quote(name)
+	createStmt, dropStmt := "CREATE DATABASE "+quoted, "DROP DATABASE IF EXISTS "+quoted
+	if source == sql.DBSource_cql {
+		createStmt, dropStmt = sql.CreateListingsDevKeyspaceStmt(name), "DROP KEYSPACE IF EXISTS "+name
+	}
+
+	// The admin connection is shared with the other tests, it is closed by the last of them
+	if err := admin.ExecStmtContext(context.Background(), createStmt); err != nil {
+		admin.Close()
+		return nil, err
+	}
+	return func() error {
+		defer admin.Close()
+		return admin.ExecStmtContext(context.Background(), dropStmt)
+	}, nil
+}
@@ -0,0 +1,41 @@
+package sqltest
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		name string
+		want *regexp.Regexp
+	}{
+		{
+			name: "should pass; Users/Create",
+			want: regexp.MustCompile(`^test_testname_should_pass__users_create_[0-9a-f]{8}$`),
+		},
+		{
+			name: "should pass; " + strings.Repeat("long", 20),
+			want: regexp.MustCompile(`^test_testname_should_pass__(long)+l?o?n?g?_[0-9a-f]{8}$`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Name(t)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.want.MatchString(got) || len(got) > maxNameLen {
+				t.Errorf("Name() = %q, want %v of at most %d characters", got, tt.want, maxNameLen)
+			}
+			other, err := Name(t)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if other == got {
+				t.Errorf("Name() = %q twice", got)
+			}
+		})
+	}
+}
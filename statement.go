@@ -1,27 +1,322 @@
 package sql
 
 import (
+	"errors"
 	"fmt"
-	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
 )
 
+// placeholder is a bind parameter found in a statement
+type placeholder struct {
+	// start and end are the byte offsets of the placeholder in the statement
+	start, end int
+	// index is the position of the parameter, $2 is 1 and the third ? is 2
+	index int
+}
+
+// ToNamedStatement converts the positional placeholders in stmt to :name placeholders using names.
+// The statement is returned unchanged if it can not be converted, use CompileNamedStatement to get the error.
 func ToNamedStatement(dbSource DBSource, stmt string, names []string) string {
-	var r *regexp.Regexp
-	switch dbSource {
-	case DBSource_mysql, DBSource_postgres, DBSource_sqlite:
-		r = regexp.MustCompile(`\?|(\$\d)`)
+	out, err := CompileNamedStatement(dbSource, stmt, names)
+	if err != nil {
+		return stmt
+	}
+	return out
+}
+
+// CompileNamedStatement converts the positional placeholders in stmt to :name placeholders using names.
+// ? is used for mysql, sqlite and cql, postgres accepts either $n or ?, $n can be used more than once.
+// Placeholders inside of quotes and comments are ignored and their colons are escaped as :: so that
+// the statement can be passed to sqlx or gocqlx. The :name parameters of a statement are kept,
+// the :: casts are only escaped when there are positional placeholders, ie. $1::text.
+func CompileNamedStatement(dbSource DBSource, stmt string, names []string) (string, error) {
+	placeholders, err := lexPlaceholders(dbSource, stmt)
+	if err != nil {
+		return "", err
 	}
-	var i int
-	return r.ReplaceAllStringFunc(stmt, func(s string) string {
-		defer func() { i++ }()
-		return fmt.Sprintf(":%s", names[i])
+	for _, p := range placeholders {
+		if p.index >= len(names) {
+			return "", fmt.Errorf("placeholder %s at position %d has no name, got %d names", stmt[p.start:p.end], p.start, len(names))
+		}
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(stmt) + len(names)*8)
+	last, next := 0, 0
+	err = lexStatement(dbSource, stmt, func(i int) int {
+		// the bytes skipped since the last visit are quotes and comments
+		writeEscapedColons(&sb, stmt[last:i])
+		end := i + 1
+		switch {
+		case next < len(placeholders) && placeholders[next].start == i:
+			p := placeholders[next]
+			next++
+			sb.WriteString(":")
+			sb.WriteString(names[p.index])
+			// A colon right after a name is not allowed by the named parsers, ie. $1::text
+			if p.end < len(stmt) && stmt[p.end] == ':' {
+				sb.WriteString(" ")
+			}
+			end = p.end
+		case stmt[i] == ':' && peek(stmt, i+1) == ':' && len(placeholders) > 0:
+			sb.WriteString("::::")
+			end = i + 2
+		default:
+			sb.WriteByte(stmt[i])
+		}
+		last = end
+		return end
 	})
+	if err != nil {
+		return "", err
+	}
+	writeEscapedColons(&sb, stmt[last:])
+
+	return sb.String(), nil
 }
 
+// FromQueryBuilder converts the postgres $n placeholders to ?
 func FromQueryBuilder(dbSource DBSource, stmt string) string {
-	if dbSource == DBSource_postgres {
-		return regexp.MustCompile(`\$\d`).
-			ReplaceAllString(stmt, "?")
+	if dbSource != DBSource_postgres {
+		return stmt
+	}
+
+	placeholders, err := lexPlaceholders(dbSource, stmt)
+	if err != nil {
+		return stmt
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, p := range placeholders {
+		sb.WriteString(stmt[last:p.start])
+		sb.WriteString("?")
+		last = p.end
+	}
+	sb.WriteString(stmt[last:])
+	return sb.String()
+}
+
+func writeEscapedColons(sb *strings.Builder, s string) {
+	sb.WriteString(strings.ReplaceAll(s, ":", "::"))
+}
+
+// lexPlaceholders finds the bind parameters in stmt for the dialect of dbSource
+func lexPlaceholders(dbSource DBSource, stmt string) ([]placeholder, error) {
+	var (
+		questions []placeholder
+		dollars   []placeholder
+		lexErr    error
+	)
+
+	err := lexStatement(dbSource, stmt, func(i int) int {
+		switch c := stmt[i]; {
+		case c == '$' && dbSource == DBSource_postgres:
+			end := i + 1
+			for end < len(stmt) && isDigit(stmt[end]) {
+				end++
+			}
+			n, err := strconv.Atoi(stmt[i+1 : end])
+			if err != nil || n < 1 {
+				lexErr = fmt.Errorf("invalid placeholder %s at position %d", stmt[i:end], i)
+				return len(stmt)
+			}
+			dollars = append(dollars, placeholder{start: i, end: end, index: n - 1})
+			return end
+		case c == '?':
+			// ?| and ?& are postgres jsonb operators
+			if dbSource == DBSource_postgres && (peek(stmt, i+1) == '|' || peek(stmt, i+1) == '&') {
+				return i + 2
+			}
+			questions = append(questions, placeholder{start: i, end: i + 1, index: len(questions)})
+		}
+		return i + 1
+	})
+	if err != nil {
+		return nil, err
+	}
+	if lexErr != nil {
+		return nil, lexErr
+	}
+
+	// When $n is used the ? are the postgres jsonb operator
+	if len(dollars) > 0 {
+		return dollars, nil
+	}
+	return questions, nil
+}
+
+// SplitStatements splits a script on the ; between statements, the ; inside of quotes and comments are kept.
+// Pieces that are only whitespace and comments are dropped.
+func SplitStatements(dbSource DBSource, script string) ([]string, error) {
+	var (
+		out   []string
+		start int
+		empty = true
+	)
+	err := lexStatement(dbSource, script, func(i int) int {
+		switch c := script[i]; {
+		case c == ';':
+			if !empty {
+				out = append(out, strings.TrimSpace(script[start:i]))
+			}
+			start, empty = i+1, true
+		case !unicode.IsSpace(rune(c)):
+			empty = false
+		}
+		return i + 1
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !empty {
+		out = append(out, strings.TrimSpace(script[start:]))
+	}
+	return out, nil
+}
+
+// lexStatement walks stmt skipping over quotes, comments and postgres dollar quoted strings.
+// visit is called for every other byte and returns the offset to continue from.
+func lexStatement(dbSource DBSource, stmt string, visit func(i int) int) error {
+	for i := 0; i < len(stmt); {
+		c := stmt[i]
+		switch {
+		case c == '\'':
+			end, err := skipQuoted(stmt, i, c, backslashEscapes(dbSource, stmt, i))
+			if err != nil {
+				return err
+			}
+			i = end
+		case c == '"' || (c == '`' && dbSource == DBSource_mysql):
+			end, err := skipQuoted(stmt, i, c, false)
+			if err != nil {
+				return err
+			}
+			i = end
+		case c == '-' && peek(stmt, i+1) == '-',
+			c == '#' && dbSource == DBSource_mysql,
+			c == '/' && peek(stmt, i+1) == '/' && dbSource == DBSource_cql:
+			i = skipLine(stmt, i)
+		case c == '/' && peek(stmt, i+1) == '*':
+			end, err := skipBlockComment(stmt, i, dbSource == DBSource_postgres)
+			if err != nil {
+				return err
+			}
+			i = end
+		case c == '$' && dbSource == DBSource_postgres && isIdentByte(peekBack(stmt, i)):
+			// part of an identifier
+			i++
+		case c == '$' && dbSource == DBSource_postgres && !isDigit(peek(stmt, i+1)):
+			end, err := skipDollarQuoted(stmt, i)
+			if err != nil {
+				return err
+			}
+			i = end
+		default:
+			i = visit(i)
+		}
+	}
+	return nil
+}
+
+// skipQuoted returns the offset after the closing quote, a doubled quote is an escaped quote
+func skipQuoted(stmt string, start int, quote byte, backslash bool) (int, error) {
+	for i := start + 1; i < len(stmt); i++ {
+		switch stmt[i] {
+		case '\\':
+			if backslash {
+				i++
+			}
+		case quote:
+			if peek(stmt, i+1) == quote {
+				i++
+				continue
+			}
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated %c quote at position %d", quote, start)
+}
+
+// skipDollarQuoted skips a postgres $tag$ string $tag$, a lone $ is skipped as is
+func skipDollarQuoted(stmt string, start int) (int, error) {
+	end := start + 1
+	for end < len(stmt) && stmt[end] != '$' {
+		if !isIdentByte(stmt[end]) {
+			return start + 1, nil
+		}
+		end++
+	}
+	if end >= len(stmt) {
+		return start + 1, nil
+	}
+
+	tag := stmt[start : end+1]
+	closing := strings.Index(stmt[end+1:], tag)
+	if closing < 0 {
+		return 0, fmt.Errorf("unterminated dollar quote %s at position %d", tag, start)
+	}
+	return end + 1 + closing + len(tag), nil
+}
+
+func skipLine(stmt string, start int) int {
+	if end := strings.IndexByte(stmt[start:], '\n'); end >= 0 {
+		return start + end + 1
+	}
+	return len(stmt)
+}
+
+// skipBlockComment skips a /* */ comment, postgres allows them to be nested
+func skipBlockComment(stmt string, start int, nested bool) (int, error) {
+	depth := 0
+	for i := start; i < len(stmt)-1; i++ {
+		switch {
+		case stmt[i] == '/' && stmt[i+1] == '*':
+			if depth == 0 || nested {
+				depth++
+			}
+			i++
+		case stmt[i] == '*' && stmt[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+	}
+	return 0, errors.New("unterminated block comment at position " + strconv.Itoa(start))
+}
+
+// backslashEscapes reports if a string starting at quote treats \ as an escape
+func backslashEscapes(dbSource DBSource, stmt string, quote int) bool {
+	switch dbSource {
+	case DBSource_mysql:
+		return true
+	case DBSource_postgres:
+		// E'...' escape string constants
+		prefix := peekBack(stmt, quote)
+		return (prefix == 'E' || prefix == 'e') && !isIdentByte(peekBack(stmt, quote-1))
 	}
-	return stmt
+	return false
+}
+
+func peek(stmt string, i int) byte {
+	if i < 0 || i >= len(stmt) {
+		return 0
+	}
+	return stmt[i]
+}
+
+func peekBack(stmt string, i int) byte {
+	return peek(stmt, i-1)
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isIdentByte(b byte) bool {
+	return b == '_' || b == '$' || isDigit(b) || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || b >= 0x80
 }
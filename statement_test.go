@@ -0,0 +1,235 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompileNamedStatement(t *testing.T) {
+	type args struct {
+		dbSource DBSource
+		stmt     string
+		names    []string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "should pass; postgres placeholders above 9",
+			args: args{
+				dbSource: DBSource_postgres,
+				stmt:     "INSERT INTO t VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+				names:    []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"},
+			},
+			want: "INSERT INTO t VALUES (:a, :b, :c, :d, :e, :f, :g, :h, :i, :j, :k)",
+		},
+		{
+			name: "should pass; postgres reused placeholder",
+			args: args{
+				dbSource: DBSource_postgres,
+				stmt:     "SELECT * FROM t WHERE a = $1 OR b = $1 AND c = $2",
+				names:    []string{"id", "name"},
+			},
+			want: "SELECT * FROM t WHERE a = :id OR b = :id AND c = :name",
+		},
+		{
+			name: "should pass; postgres cast and jsonb operators",
+			args: args{
+				dbSource: DBSource_postgres,
+				stmt:     "SELECT * FROM t WHERE id = $1::text AND tags ?| array['a'] AND data ? 'key'",
+				names:    []string{"id"},
+			},
+			want: "SELECT * FROM t WHERE id = :id ::::text AND tags ?| array['a'] AND data ? 'key'",
+		},
+		{
+			name: "should pass; postgres dollar quoted string and comments",
+			args: args{
+				dbSource: DBSource_postgres,
+				stmt:     "SELECT $$it's $1?$$, $tag$ $2 $tag$ /* $1 /* ? */ */ FROM t -- ?\nWHERE id = $1",
+				names:    []string{"id"},
+			},
+			want: "SELECT $$it's $1?$$, $tag$ $2 $tag$ /* $1 /* ? */ */ FROM t -- ?\nWHERE id = :id",
+		},
+		{
+			name: "should pass; postgres question mark placeholders",
+			args: args{
+				dbSource: DBSource_postgres,
+				stmt:     "SELECT * FROM users WHERE user_id=?",
+				names:    []string{"user_id"},
+			},
+			want: "SELECT * FROM users WHERE user_id=:user_id",
+		},
+		{
+			name: "should pass; mysql strings, identifiers and comments",
+			args: args{
+				dbSource: DBSource_mysql,
+				stmt:     "SELECT 'a?', 'it''s ?', 'don\\'t ?', `col?` FROM t # ?\nWHERE id = ? AND time = '12:30'",
+				names:    []string{"id"},
+			},
+			want: "SELECT 'a?', 'it''s ?', 'don\\'t ?', `col?` FROM t # ?\nWHERE id = :id AND time = '12::30'",
+		},
+		{
+			name: "should pass; sqlite",
+			args: args{
+				dbSource: DBSource_sqlite,
+				stmt:     "SELECT * FROM t WHERE a = ? AND b = ?",
+				names:    []string{"a", "b"},
+			},
+			want: "SELECT * FROM t WHERE a = :a AND b = :b",
+		},
+		{
+			name: "should pass; cql",
+			args: args{
+				dbSource: DBSource_cql,
+				stmt:     "SELECT * FROM users WHERE user_id=? // ?",
+				names:    []string{"user_id"},
+			},
+			want: "SELECT * FROM users WHERE user_id=:user_id // ?",
+		},
+		{
+			name: "should pass; named parameters without names",
+			args: args{
+				dbSource: DBSource_sqlite,
+				stmt:     "INSERT INTO u (id, name) VALUES (:id, :name) -- 12:30",
+			},
+			want: "INSERT INTO u (id, name) VALUES (:id, :name) -- 12::30",
+		},
+		{
+			name: "should pass; postgres named parameters keep the escaped casts",
+			args: args{
+				dbSource: DBSource_postgres,
+				stmt:     "SELECT * FROM t WHERE id = :id::::text AND time = '12:30'",
+			},
+			want: "SELECT * FROM t WHERE id = :id::::text AND time = '12::30'",
+		},
+		{
+			name: "should fail; fewer names than placeholders",
+			args: args{
+				dbSource: DBSource_mysql,
+				stmt:     "SELECT * FROM t WHERE a = ? AND b = ?",
+				names:    []string{"a"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "should fail; $0",
+			args: args{
+				dbSource: DBSource_postgres,
+				stmt:     "SELECT * FROM t WHERE a = $0",
+				names:    []string{"a"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "should fail; unterminated string",
+			args: args{
+				dbSource: DBSource_postgres,
+				stmt:     "SELECT * FROM t WHERE a = 'b AND c = $1",
+				names:    []string{"a"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CompileNamedStatement(tt.args.dbSource, tt.args.stmt, tt.args.names)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CompileNamedStatement() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CompileNamedStatement() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromQueryBuilder(t *testing.T) {
+	tests := []struct {
+		name     string
+		dbSource DBSource
+		stmt     string
+		want     string
+	}{
+		{
+			name:     "should pass; postgres",
+			dbSource: DBSource_postgres,
+			stmt:     "INSERT INTO t (a, b) VALUES ($1, '$2'), ($11, $12)",
+			want:     "INSERT INTO t (a, b) VALUES (?, '$2'), (?, ?)",
+		},
+		{
+			name:     "should pass; mysql is unchanged",
+			dbSource: DBSource_mysql,
+			stmt:     "INSERT INTO t (a) VALUES (?)",
+			want:     "INSERT INTO t (a) VALUES (?)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromQueryBuilder(tt.dbSource, tt.stmt); got != tt.want {
+				t.Errorf("FromQueryBuilder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	type args struct {
+		dbSource DBSource
+		script   string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "should pass; postgres function body and comments",
+			args: args{
+				dbSource: DBSource_postgres,
+				script: `-- users
+CREATE TABLE users (id int); /* ; */
+CREATE FUNCTION f() RETURNS void AS $$ BEGIN; END; $$ LANGUAGE plpgsql;
+INSERT INTO users VALUES (';');
+-- trailing comment;
+`,
+			},
+			want: []string{
+				"-- users\nCREATE TABLE users (id int)",
+				"/* ; */\nCREATE FUNCTION f() RETURNS void AS $$ BEGIN; END; $$ LANGUAGE plpgsql",
+				"INSERT INTO users VALUES (';')",
+			},
+		},
+		{
+			name: "should pass; cql without a trailing semicolon",
+			args: args{
+				dbSource: DBSource_cql,
+				script:   "CREATE TABLE a (id int PRIMARY KEY);\n\n// b;\nCREATE TABLE b (id int PRIMARY KEY)\n",
+			},
+			want: []string{"CREATE TABLE a (id int PRIMARY KEY)", "// b;\nCREATE TABLE b (id int PRIMARY KEY)"},
+		},
+		{
+			name: "should fail; unterminated quote",
+			args: args{
+				dbSource: DBSource_mysql,
+				script:   "INSERT INTO t VALUES ('a);",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitStatements(tt.args.dbSource, tt.args.script)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SplitStatements() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Error(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package sql
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const defaultStmtCacheSize = 128
+
+// StmtCacheStats are the counters of the prepared statement cache
+type StmtCacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Size      int    `json:"size"`
+}
+
+// HitRate is the ratio of hits to lookups, 0 when there were no lookups
+func (s StmtCacheStats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// stmtCache is a LRU cache of prepared named statements keyed by the named statement.
+// Entries are reference counted so an evicted statement is only closed after its last user released it.
+type stmtCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	m     map[string]*list.Element
+	stats StmtCacheStats
+}
+
+type stmtCacheEntry struct {
+	key     string
+	stmt    *sqlx.NamedStmt
+	refs    int
+	evicted bool
+}
+
+func newStmtCache(size int) *stmtCache {
+	return &stmtCache{
+		size: size,
+		ll:   list.New(),
+		m:    map[string]*list.Element{},
+	}
+}
+
+// prepare returns the cached statement for query or prepares it, release must be called once done with the statement
+func (c *stmtCache) prepare(ctx context.Context, db *sqlx.DB, query string) (*sqlx.NamedStmt, func(), error) {
+	c.mu.Lock()
+	if el, ok := c.m[query]; ok {
+		c.stats.Hits++
+		c.ll.MoveToFront(el)
+		entry := el.Value.(*stmtCacheEntry)
+		entry.refs++
+		c.mu.Unlock()
+		return entry.stmt, c.releaseFunc(entry), nil
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	// Prepare outside of the lock so that a slow prepare does not block the hits
+	stmt, err := db.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another caller prepared the same statement in the meantime
+	if el, ok := c.m[query]; ok {
+		stmt.Close()
+		c.ll.MoveToFront(el)
+		entry := el.Value.(*stmtCacheEntry)
+		entry.refs++
+		return entry.stmt, c.releaseFunc(entry), nil
+	}
+
+	entry := &stmtCacheEntry{key: query, stmt: stmt, refs: 1}
+	c.m[query] = c.ll.PushFront(entry)
+	for c.ll.Len() > c.size {
+		c.evict(c.ll.Back())
+	}
+	return stmt, c.releaseFunc(entry), nil
+}
+
+func (c *stmtCache) releaseFunc(entry *stmtCacheEntry) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			entry.refs--
+			if entry.evicted && entry.refs == 0 {
+				entry.stmt.Close()
+			}
+		})
+	}
+}
+
+// evict must be called with the lock held
+func (c *stmtCache) evict(el *list.Element) {
+	entry := c.ll.Remove(el).(*stmtCacheEntry)
+	delete(c.m, entry.key)
+	c.stats.Evictions++
+	entry.evicted = true
+	if entry.refs == 0 {
+		entry.stmt.Close()
+	}
+}
+
+// close evicts every statement
+func (c *stmtCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.ll.Len() > 0 {
+		c.evict(c.ll.Back())
+	}
+}
+
+func (c *stmtCache) Stats() StmtCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.ll.Len()
+	return stats
+}
+
+// prepareNamed prepares stmt as a named statement, using the statement cache when it is enabled.
+// release must be called once done with the statement.
+func (o *DB) prepareNamed(ctx context.Context, stmt string, names []string) (*sqlx.NamedStmt, func(), error) {
+	query, err := CompileNamedStatement(o.DBSource, stmt, names)
+	if err != nil {
+		return nil, nil, err
+	}
+	if o.stmts == nil {
+		namedStmt, err := o.sql.PrepareNamedContext(ctx, query)
+		if err != nil {
+			return nil, nil, err
+		}
+		return namedStmt, func() { namedStmt.Close() }, nil
+	}
+	return o.stmts.prepare(ctx, o.sql, query)
+}
+
+// StmtCacheStats returns the hit, miss and eviction counters of the prepared statement cache
+func (o *DB) StmtCacheStats() StmtCacheStats {
+	if o.stmts == nil {
+		return StmtCacheStats{}
+	}
+	return o.stmts.Stats()
+}
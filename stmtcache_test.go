@@ -0,0 +1,106 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jmoiron/sqlx"
+)
+
+// countingDriver is a driver that only supports preparing statements and counts the closed ones
+type countingDriver struct {
+	closed int64
+}
+
+func (d *countingDriver) Open(name string) (driver.Conn, error) { return countingConn{d}, nil }
+
+type countingConn struct{ d *countingDriver }
+
+func (c countingConn) Prepare(query string) (driver.Stmt, error) { return countingStmt{c.d}, nil }
+func (c countingConn) Close() error                              { return nil }
+func (c countingConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type countingStmt struct{ d *countingDriver }
+
+func (s countingStmt) Close() error {
+	atomic.AddInt64(&s.d.closed, 1)
+	return nil
+}
+func (s countingStmt) NumInput() int { return -1 }
+func (s countingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (s countingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+var testCountingDriver = &countingDriver{}
+
+func init() {
+	sql.Register("counting", testCountingDriver)
+}
+
+func Test_stmtCache(t *testing.T) {
+	db, err := sqlx.Open("counting", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	closed := func() int64 { return atomic.LoadInt64(&testCountingDriver.closed) }
+	start := closed()
+
+	cache := newStmtCache(2)
+
+	for _, query := range []string{"a", "b", "a"} {
+		_, release, err := cache.prepare(ctx, db, query)
+		if err != nil {
+			t.Fatalf("prepare %s: %v", query, err)
+		}
+		release()
+	}
+
+	// "b" is the least recently used and is still in use when it is evicted
+	_, releaseB, err := cache.prepare(ctx, db, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, release, err := cache.prepare(ctx, db, "c"); err != nil {
+		t.Fatal(err)
+	} else {
+		release()
+	}
+	if _, release, err := cache.prepare(ctx, db, "d"); err != nil {
+		t.Fatal(err)
+	} else {
+		release()
+	}
+
+	// "a" was evicted and closed, "b" is only closed once it is released
+	if got := closed() - start; got != 1 {
+		t.Errorf("closed statements = %d, want 1", got)
+	}
+	releaseB()
+	releaseB()
+	if got := closed() - start; got != 2 {
+		t.Errorf("closed statements = %d, want 2", got)
+	}
+
+	want := StmtCacheStats{Hits: 2, Misses: 4, Evictions: 2, Size: 2}
+	if got := cache.Stats(); !cmp.Equal(got, want) {
+		t.Error(cmp.Diff(got, want))
+	}
+	if got := cache.Stats().HitRate(); got != 2.0/6.0 {
+		t.Errorf("StmtCacheStats.HitRate() = %v, want %v", got, 2.0/6.0)
+	}
+
+	cache.close()
+	if got := closed() - start; got != 4 {
+		t.Errorf("closed statements = %d, want 4", got)
+	}
+}
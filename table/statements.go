@@ -0,0 +1,162 @@
+package table
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Dialect is the flavour of sql the statements are generated for, the values match sql.DBSource
+type Dialect string
+
+const (
+	Dialect_postgres Dialect = "postgres"
+	Dialect_mysql    Dialect = "mysql"
+	Dialect_sqlite   Dialect = "sqlite"
+	Dialect_cql      Dialect = "cql"
+)
+
+var (
+	ErrNoPrimaryKey = errors.New("table has no primary key")
+	ErrNoColumns    = errors.New("table has no columns")
+)
+
+// Quote quotes an identifier, mysql uses backticks and the others use double quotes
+func (d Dialect) Quote(name string) string {
+	if d == Dialect_mysql {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// Placeholder returns the bind parameter for the n-th (1 based) argument
+func (d Dialect) Placeholder(n int) string {
+	if d == Dialect_postgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+func (d Dialect) isValid() error {
+	switch d {
+	case Dialect_postgres, Dialect_mysql, Dialect_sqlite, Dialect_cql:
+		return nil
+	}
+	return fmt.Errorf("dialect %q is not supported", d)
+}
+
+// SortedColumns returns the column names in a stable order
+func (t Table) SortedColumns() []string {
+	out := t.ListColumns()
+	sort.Strings(out)
+	return out
+}
+
+// Insert returns an insert of every column and the names of the placeholders
+func (t Table) Insert(d Dialect) (string, []string, error) {
+	if err := d.isValid(); err != nil {
+		return "", nil, err
+	}
+	names := t.SortedColumns()
+	if len(names) == 0 {
+		return "", nil, ErrNoColumns
+	}
+
+	placeholders := make([]string, len(names))
+	for i := range names {
+		placeholders[i] = d.Placeholder(i + 1)
+	}
+
+	stmt := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		d.Quote(t.Name), t.quoteList(d, names), strings.Join(placeholders, ", "))
+	return stmt, names, nil
+}
+
+// Update returns an update of every column that is not part of the primary key, by the primary key
+func (t Table) Update(d Dialect) (string, []string, error) {
+	if err := d.isValid(); err != nil {
+		return "", nil, err
+	}
+	keys := t.PrimaryKey()
+	if len(keys) == 0 {
+		return "", nil, ErrNoPrimaryKey
+	}
+	columns := t.OmitColumns(keys...)
+	sort.Strings(columns)
+	if len(columns) == 0 {
+		return "", nil, ErrNoColumns
+	}
+
+	sets := make([]string, len(columns))
+	for i, column := range columns {
+		sets[i] = fmt.Sprintf("%s = %s", d.Quote(column), d.Placeholder(i+1))
+	}
+
+	where, names := t.whereKeys(d, len(columns))
+	stmt := fmt.Sprintf("UPDATE %s SET %s WHERE %s", d.Quote(t.Name), strings.Join(sets, ", "), where)
+	return stmt, append(columns, names...), nil
+}
+
+// Delete returns a delete by the primary key
+func (t Table) Delete(d Dialect) (string, []string, error) {
+	if err := d.isValid(); err != nil {
+		return "", nil, err
+	}
+	if len(t.PrimaryKey()) == 0 {
+		return "", nil, ErrNoPrimaryKey
+	}
+
+	where, names := t.whereKeys(d, 0)
+	return fmt.Sprintf("DELETE FROM %s WHERE %s", d.Quote(t.Name), where), names, nil
+}
+
+// SelectByKey returns a select of every column by the primary key
+func (t Table) SelectByKey(d Dialect) (string, []string, error) {
+	if err := d.isValid(); err != nil {
+		return "", nil, err
+	}
+	if len(t.PrimaryKey()) == 0 {
+		return "", nil, ErrNoPrimaryKey
+	}
+	columns := t.SortedColumns()
+	if len(columns) == 0 {
+		return "", nil, ErrNoColumns
+	}
+
+	where, names := t.whereKeys(d, 0)
+	stmt := fmt.Sprintf("SELECT %s FROM %s WHERE %s", t.quoteList(d, columns), d.Quote(t.Name), where)
+	return stmt, names, nil
+}
+
+// SelectAll returns a select of every column of every row
+func (t Table) SelectAll(d Dialect) (string, []string, error) {
+	if err := d.isValid(); err != nil {
+		return "", nil, err
+	}
+	columns := t.SortedColumns()
+	if len(columns) == 0 {
+		return "", nil, ErrNoColumns
+	}
+
+	return fmt.Sprintf("SELECT %s FROM %s", t.quoteList(d, columns), d.Quote(t.Name)), []string{}, nil
+}
+
+// whereKeys returns the conditions on the primary key, offset is the number of placeholders before it
+func (t Table) whereKeys(d Dialect, offset int) (string, []string) {
+	keys := t.PrimaryKey()
+	conditions := make([]string, len(keys))
+	for i, key := range keys {
+		conditions[i] = fmt.Sprintf("%s = %s", d.Quote(key), d.Placeholder(offset+i+1))
+	}
+	return strings.Join(conditions, " AND "), keys
+}
+
+func (t Table) quoteList(d Dialect, names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = d.Quote(name)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,124 @@
+package table
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var testUserGroupsTable = New("user_groups", columns{
+	"group_id":   {},
+	"user_id":    {},
+	"created_at": {},
+	"created_by": {},
+}).WithKeys([]string{"group_id"}, "user_id")
+
+func TestTable_statements(t *testing.T) {
+	type gen func(Table, Dialect) (string, []string, error)
+	tests := []struct {
+		name      string
+		table     Table
+		dialect   Dialect
+		gen       gen
+		want      string
+		wantNames []string
+		wantErr   error
+	}{
+		{
+			name:      "should pass; postgres insert",
+			table:     testUserGroupsTable,
+			dialect:   Dialect_postgres,
+			gen:       Table.Insert,
+			want:      `INSERT INTO "user_groups" ("created_at", "created_by", "group_id", "user_id") VALUES ($1, $2, $3, $4)`,
+			wantNames: []string{"created_at", "created_by", "group_id", "user_id"},
+		},
+		{
+			name:      "should pass; mysql insert",
+			table:     testUserGroupsTable,
+			dialect:   Dialect_mysql,
+			gen:       Table.Insert,
+			want:      "INSERT INTO `user_groups` (`created_at`, `created_by`, `group_id`, `user_id`) VALUES (?, ?, ?, ?)",
+			wantNames: []string{"created_at", "created_by", "group_id", "user_id"},
+		},
+		{
+			name:      "should pass; postgres update",
+			table:     testUserGroupsTable,
+			dialect:   Dialect_postgres,
+			gen:       Table.Update,
+			want:      `UPDATE "user_groups" SET "created_at" = $1, "created_by" = $2 WHERE "group_id" = $3 AND "user_id" = $4`,
+			wantNames: []string{"created_at", "created_by", "group_id", "user_id"},
+		},
+		{
+			name:      "should pass; cql delete",
+			table:     testUserGroupsTable,
+			dialect:   Dialect_cql,
+			gen:       Table.Delete,
+			want:      `DELETE FROM "user_groups" WHERE "group_id" = ? AND "user_id" = ?`,
+			wantNames: []string{"group_id", "user_id"},
+		},
+		{
+			name:      "should pass; sqlite select by key",
+			table:     testUserGroupsTable,
+			dialect:   Dialect_sqlite,
+			gen:       Table.SelectByKey,
+			want:      `SELECT "created_at", "created_by", "group_id", "user_id" FROM "user_groups" WHERE "group_id" = ? AND "user_id" = ?`,
+			wantNames: []string{"group_id", "user_id"},
+		},
+		{
+			name:      "should pass; mysql select all",
+			table:     testUserGroupsTable,
+			dialect:   Dialect_mysql,
+			gen:       Table.SelectAll,
+			want:      "SELECT `created_at`, `created_by`, `group_id`, `user_id` FROM `user_groups`",
+			wantNames: []string{},
+		},
+		{
+			name:    "should fail; update without a primary key",
+			table:   New("users", columns{"user_id": {}}),
+			dialect: Dialect_postgres,
+			gen:     Table.Update,
+			wantErr: ErrNoPrimaryKey,
+		},
+		{
+			name:    "should fail; insert without columns",
+			table:   New("users", columns{}),
+			dialect: Dialect_postgres,
+			gen:     Table.Insert,
+			wantErr: ErrNoColumns,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotNames, err := tt.gen(tt.table, tt.dialect)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got = %v, want %v", got, tt.want)
+			}
+			if !cmp.Equal(gotNames, tt.wantNames) {
+				t.Error(cmp.Diff(gotNames, tt.wantNames))
+			}
+		})
+	}
+}
+
+func TestDialect_Quote(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		in      string
+		want    string
+	}{
+		{name: "should pass; postgres", dialect: Dialect_postgres, in: `we"ird`, want: `"we""ird"`},
+		{name: "should pass; mysql", dialect: Dialect_mysql, in: "we`ird", want: "`we``ird`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dialect.Quote(tt.in); got != tt.want {
+				t.Errorf("Dialect.Quote() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
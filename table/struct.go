@@ -0,0 +1,155 @@
+package table
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	cqlreflectx "github.com/scylladb/go-reflectx"
+)
+
+// KeyTag is the struct tag used to mark the key columns, the values are partition, clustering and primary
+const KeyTag = "table"
+
+const (
+	keyPartition  = "partition"
+	keyClustering = "clustering"
+	keyPrimary    = "primary"
+)
+
+type structOptions struct {
+	dialect    Dialect
+	tagName    string
+	mapFunc    func(string) string
+	tagMapFunc func(string) string
+}
+
+// Option interface to change how FromStruct maps fields to columns
+type Option interface {
+	applyOption(*structOptions) error
+}
+
+type optionApplyFunc func(*structOptions) error
+
+func (f optionApplyFunc) applyOption(o *structOptions) error {
+	return f(o)
+}
+
+// WithMapFunc maps the names of the fields without a tag, the same as sql.WithMapFunc
+func WithMapFunc(mapFunc func(string) string) Option {
+	return optionApplyFunc(func(o *structOptions) error {
+		o.mapFunc = mapFunc
+		return nil
+	})
+}
+
+// WithTagMapFunc maps the names in the tags, the same as sql.WithTagMapFunc
+func WithTagMapFunc(tagMapFunc func(string) string) Option {
+	return optionApplyFunc(func(o *structOptions) error {
+		o.tagMapFunc = tagMapFunc
+		return nil
+	})
+}
+
+// WithTagName sets the tag the column names are read from, it defaults to json
+func WithTagName(tagName string) Option {
+	return optionApplyFunc(func(o *structOptions) error {
+		o.tagName = tagName
+		return nil
+	})
+}
+
+// WithDialect sets the Definitions of the columns to the types of the fields in the dialect,
+// the key columns are not null and the others are nullable
+func WithDialect(d Dialect) Option {
+	return optionApplyFunc(func(o *structOptions) error {
+		if err := d.isValid(); err != nil {
+			return err
+		}
+		o.dialect = d
+		return nil
+	})
+}
+
+// FromStruct builds a table from the exported fields of T. The column names are mapped from the json tags
+// in the same way as sql.DB does, fields tagged with "-" are skipped and embedded structs are flattened.
+// Keys are marked with the table tag:
+//
+//	type UserGroup struct {
+//		GroupID string `json:"groupId" table:"partition"`
+//		UserID  string `json:"userId" table:"clustering"`
+//	}
+//
+// For sql primary and partition are the same.
+func FromStruct[T any](name string, opts ...Option) (Table, error) {
+	o := &structOptions{
+		tagName:    "json",
+		mapFunc:    cqlreflectx.CamelToSnakeASCII,
+		tagMapFunc: cqlreflectx.CamelToSnakeASCII,
+	}
+	for _, opt := range opts {
+		if err := opt.applyOption(o); err != nil {
+			return Table{}, err
+		}
+	}
+
+	t := cqlreflectx.Deref(reflect.TypeOf((*T)(nil)).Elem())
+	if t.Kind() != reflect.Struct {
+		return Table{}, fmt.Errorf("expected a struct, got %s", t)
+	}
+
+	mapper := cqlreflectx.NewMapperTagFunc(o.tagName, PreMapFunc(o.mapFunc), PreMapFunc(o.tagMapFunc))
+	tm := mapper.TypeMap(t)
+
+	out := New(name, columns{})
+	if o.dialect != "" {
+		out.Definitions = map[string]Column{}
+	}
+	for _, fi := range tm.Index {
+		if fi.Embedded || !isColumn(fi, tm.Tree) {
+			continue
+		}
+		out.Columns[fi.Name] = struct{}{}
+
+		key := fi.Field.Tag.Get(KeyTag)
+		switch key {
+		case keyPartition, keyPrimary:
+			out.PartitionKey = append(out.PartitionKey, fi.Name)
+		case keyClustering:
+			out.ClusteringKey = append(out.ClusteringKey, fi.Name)
+		case "":
+		default:
+			return Table{}, fmt.Errorf("field %s has an unknown %s tag %q", fi.Field.Name, KeyTag, key)
+		}
+		if out.Definitions != nil && key == "" {
+			out.Definitions[fi.Name] = Column{Type: o.dialect.ColumnType(fi.Field.Type), Nullable: true}
+		} else if out.Definitions != nil {
+			out.Definitions[fi.Name] = Column{Type: o.dialect.KeyColumnType(fi.Field.Type)}
+		}
+	}
+
+	return out, nil
+}
+
+// isColumn reports if the field belongs to the struct itself or to a struct embedded in it,
+// the fields of nested structs (ie. udts) are part of their parent column
+func isColumn(fi *cqlreflectx.FieldInfo, root *cqlreflectx.FieldInfo) bool {
+	for p := fi.Parent; p != root; p = p.Parent {
+		if p == nil || !p.Embedded {
+			return false
+		}
+	}
+	return true
+}
+
+// PreMapFunc only maps the name of a tag and drops the options, ie. omitempty.
+// A "-" is kept as is so the field is skipped.
+func PreMapFunc(f func(string) string) func(string) string {
+	return func(s string) string {
+		name := strings.Split(s, ",")[0]
+		if name == "-" {
+			return name
+		}
+		return f(name)
+	}
+}
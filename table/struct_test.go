@@ -0,0 +1,173 @@
+package table
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	test_users "github.com/joematpal/go-sql/v2/test/users"
+	cqlreflectx "github.com/scylladb/go-reflectx"
+)
+
+type testAudit struct {
+	CreatedAt int64  `json:"createdAt,omitempty"`
+	CreatedBy string `json:"createdBy,omitempty"`
+}
+
+type testGroup struct {
+	testAudit
+	GroupID  string            `json:"groupId" table:"partition"`
+	Name     string            `json:"name,omitempty"`
+	Scope    *test_users.Scope `json:"scope"`
+	Secret   string            `json:"-"`
+	internal string
+	Untagged bool
+}
+
+func TestFromStruct(t *testing.T) {
+	identity := func(s string) string { return s }
+
+	tests := []struct {
+		name    string
+		build   func() (Table, error)
+		want    Table
+		wantErr bool
+	}{
+		{
+			name:  "should pass; user groups",
+			build: func() (Table, error) { return FromStruct[test_users.UserGroup]("user_groups") },
+			want: Table{
+				Name: "user_groups",
+				Columns: columns{
+					"group_id": {}, "user_id": {}, "created_at": {}, "updated_at": {}, "created_by": {}, "updated_by": {},
+				},
+				PartitionKey:  []string{"user_id"},
+				ClusteringKey: []string{"group_id"},
+			},
+		},
+		{
+			name:  "should pass; embedded, nested, skipped and unexported fields",
+			build: func() (Table, error) { return FromStruct[*testGroup]("groups") },
+			want: Table{
+				Name: "groups",
+				Columns: columns{
+					"group_id": {}, "name": {}, "scope": {}, "untagged": {}, "created_at": {}, "created_by": {},
+				},
+				PartitionKey: []string{"group_id"},
+			},
+		},
+		{
+			name: "should pass; identity tag map func",
+			build: func() (Table, error) {
+				return FromStruct[test_users.User]("users", WithTagMapFunc(identity))
+			},
+			want: Table{
+				Name: "users",
+				Columns: columns{
+					"userId": {}, "username": {}, "email": {}, "verified": {}, "createdAt": {}, "updatedAt": {}, "createdBy": {}, "updatedBy": {},
+				},
+				PartitionKey: []string{"userId"},
+			},
+		},
+		{
+			name: "should pass; cql definitions",
+			build: func() (Table, error) {
+				return FromStruct[test_users.UserSettings]("user_settings", WithDialect(Dialect_cql))
+			},
+			want: Table{
+				Name: "user_settings",
+				Columns: columns{
+					"user_id": {}, "metadata": {}, "scope": {}, "created_at": {}, "updated_at": {}, "created_by": {}, "updated_by": {},
+				},
+				Definitions: map[string]Column{
+					"user_id":    {Type: "text"},
+					"metadata":   {Type: "map<text, text>", Nullable: true},
+					"scope":      {Type: "frozen<scope>", Nullable: true},
+					"created_at": {Type: "bigint", Nullable: true},
+					"updated_at": {Type: "bigint", Nullable: true},
+					"created_by": {Type: "text", Nullable: true},
+					"updated_by": {Type: "text", Nullable: true},
+				},
+				PartitionKey: []string{"user_id"},
+			},
+		},
+		{
+			name:    "should fail; unknown dialect",
+			build:   func() (Table, error) { return FromStruct[test_users.User]("users", WithDialect("oracle")) },
+			wantErr: true,
+		},
+		{
+			name:    "should fail; not a struct",
+			build:   func() (Table, error) { return FromStruct[string]("strings") },
+			wantErr: true,
+		},
+		{
+			name: "should fail; unknown key",
+			build: func() (Table, error) {
+				return FromStruct[struct {
+					ID string `json:"id" table:"sort"`
+				}]("bad")
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.build()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FromStruct() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Error(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestPreMapFunc(t *testing.T) {
+	type args struct {
+		f   func(string) string
+		tag string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+
+		{
+			name: "should pass camel to snake; value: userId,omitempty",
+			args: args{
+				f:   cqlreflectx.CamelToSnakeASCII,
+				tag: "userId,comitempty",
+			},
+			want: "user_id",
+		},
+		{
+			name: "should pass camel to snake; value: userId",
+			args: args{
+				f:   cqlreflectx.CamelToSnakeASCII,
+				tag: "userId",
+			},
+			want: "user_id",
+		},
+		{
+			name: "should pass skipped field; value: -",
+			args: args{
+				f:   cqlreflectx.CamelToSnakeASCII,
+				tag: "-",
+			},
+			want: "-",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PreMapFunc(tt.args.f); !reflect.DeepEqual(got(tt.args.tag), tt.want) {
+				t.Errorf("PreMapFunc() = %v, want %v", got(tt.args.tag), tt.want)
+			}
+		})
+	}
+}
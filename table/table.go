@@ -2,9 +2,21 @@ package table
 
 type columns = map[string]struct{}
 
+// Column is the definition of a column as described by the database
+type Column struct {
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+}
+
 type Table struct {
 	Name    string `json:"name"`
 	Columns columns
+	// Definitions are the types of the columns, only set when the table is described from a database
+	Definitions map[string]Column `json:"definitions,omitempty"`
+	// PartitionKey are the columns that make up the partition key for cql, or the primary key for sql
+	PartitionKey []string `json:"partitionKey,omitempty"`
+	// ClusteringKey are the cql clustering columns, for sql they are appended to the primary key
+	ClusteringKey []string `json:"clusteringKey,omitempty"`
 }
 
 func New(name string, columns columns) Table {
@@ -14,6 +26,20 @@ func New(name string, columns columns) Table {
 	}
 }
 
+// WithKeys returns a copy of the table with the partition and clustering keys set
+func (t Table) WithKeys(partitionKey []string, clusteringKey ...string) Table {
+	t.PartitionKey = partitionKey
+	t.ClusteringKey = clusteringKey
+	return t
+}
+
+// PrimaryKey returns the partition key followed by the clustering key
+func (t Table) PrimaryKey() []string {
+	out := make([]string, 0, len(t.PartitionKey)+len(t.ClusteringKey))
+	out = append(out, t.PartitionKey...)
+	return append(out, t.ClusteringKey...)
+}
+
 func (t Table) GetName() string {
 	return t.Name
 }
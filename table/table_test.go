@@ -108,3 +108,30 @@ func TestTable_ListColumns(t *testing.T) {
 		})
 	}
 }
+
+func TestTable_PrimaryKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		table Table
+		want  []string
+	}{
+		{
+			name:  "should pass; no keys",
+			table: New("test_table", columns{"xid": {}}),
+			want:  []string{},
+		},
+		{
+			name: "should pass; partition and clustering keys",
+			table: New("test_table", columns{"group_id": {}, "user_id": {}, "name": {}}).
+				WithKeys([]string{"group_id"}, "user_id"),
+			want: []string{"group_id", "user_id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.table.PrimaryKey(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Table.PrimaryKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package table
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	cqlreflectx "github.com/scylladb/go-reflectx"
+)
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	bytesType = reflect.TypeOf([]byte(nil))
+)
+
+// MySQLKeyLength is the length of the string and bytes key columns of mysql,
+// it can't index text and blob columns without a prefix length
+const MySQLKeyLength = 255
+
+// ColumnType returns the column type for a go type, structs are frozen udts named after the type for cql
+// and json for the sql sources
+func (d Dialect) ColumnType(t reflect.Type) string {
+	t = cqlreflectx.Deref(t)
+	switch t {
+	case timeType:
+		switch d {
+		case Dialect_postgres:
+			return "timestamp with time zone"
+		case Dialect_cql:
+			return "timestamp"
+		}
+		return "datetime"
+	case bytesType:
+		if d == Dialect_postgres {
+			return "bytea"
+		}
+		return "blob"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int8:
+		if d == Dialect_cql {
+			return "tinyint"
+		}
+		return "smallint"
+	case reflect.Int16, reflect.Uint8:
+		return "smallint"
+	case reflect.Int32, reflect.Uint16:
+		if d == Dialect_cql {
+			return "int"
+		}
+		return "integer"
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return "bigint"
+	case reflect.Float32:
+		switch d {
+		case Dialect_postgres, Dialect_sqlite:
+			return "real"
+		}
+		return "float"
+	case reflect.Float64:
+		if d == Dialect_postgres {
+			return "double precision"
+		}
+		return "double"
+	case reflect.String:
+		return "text"
+	}
+
+	if d != Dialect_cql {
+		switch d {
+		case Dialect_postgres:
+			return "jsonb"
+		case Dialect_mysql:
+			return "json"
+		}
+		return "text"
+	}
+
+	switch t.Kind() {
+	case reflect.Map:
+		return "map<" + d.ColumnType(t.Key()) + ", " + d.ColumnType(t.Elem()) + ">"
+	case reflect.Slice, reflect.Array:
+		return "list<" + d.ColumnType(t.Elem()) + ">"
+	case reflect.Struct:
+		return "frozen<" + cqlreflectx.CamelToSnakeASCII(t.Name()) + ">"
+	}
+	return "blob"
+}
+
+// KeyColumnType is the ColumnType of a key column, mysql strings and bytes are
+// varchar and varbinary of MySQLKeyLength so that they can be in the primary key
+func (d Dialect) KeyColumnType(t reflect.Type) string {
+	if d == Dialect_mysql {
+		switch t = cqlreflectx.Deref(t); {
+		case t == bytesType:
+			return fmt.Sprintf("varbinary(%d)", MySQLKeyLength)
+		case t.Kind() == reflect.String:
+			return fmt.Sprintf("varchar(%d)", MySQLKeyLength)
+		}
+	}
+	return d.ColumnType(t)
+}
+
+// normalizeType lowercases a type and drops the aliases and display widths that
+// the databases return when a table is described
+func (d Dialect) normalizeType(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch d {
+	case Dialect_mysql:
+		switch s {
+		case "tinyint(1)", "bool":
+			return "boolean"
+		case "integer":
+			return "int"
+		}
+		// int display widths, ie. bigint(20)
+		if i := strings.Index(s, "int("); i >= 0 {
+			if j := strings.Index(s[i:], ")"); j >= 0 {
+				s = s[:i+3] + s[i+j+1:]
+			}
+		}
+	case Dialect_postgres:
+		switch s {
+		case "int", "int4":
+			return "integer"
+		case "int8":
+			return "bigint"
+		case "bool":
+			return "boolean"
+		case "timestamptz":
+			return "timestamp with time zone"
+		}
+	case Dialect_cql:
+		if s == "varchar" {
+			return "text"
+		}
+	}
+	return s
+}
+
+// SameType reports if two column types are the same type once normalized
+func (d Dialect) SameType(a, b string) bool {
+	return d.normalizeType(a) == d.normalizeType(b)
+}
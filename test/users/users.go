@@ -8,7 +8,7 @@ import (
 )
 
 type User struct {
-	UserID    string `json:"userId,omitempty"`
+	UserID    string `json:"userId,omitempty" table:"primary"`
 	Username  string `json:"username,omitempty"`
 	Email     string `json:"email,omitempty"`
 	Verified  bool   `json:"verified,omitempty"`
@@ -19,7 +19,7 @@ type User struct {
 }
 
 type UserSettings struct {
-	UserId    string            `json:"user_id,omitempty"`
+	UserId    string            `json:"user_id,omitempty" table:"primary"`
 	Metadata  map[string]string `json:"metadata,omitempty"`
 	Scope     *Scope            `json:"scope,omitempty"`
 	CreatedAt int64             `json:"created_at,omitempty"`
@@ -29,8 +29,8 @@ type UserSettings struct {
 }
 
 type UserGroup struct {
-	GroupID   string `json:"groupId"`
-	UserID    string `json:"userId"`
+	GroupID   string `json:"groupId" table:"clustering"`
+	UserID    string `json:"userId" table:"partition"`
 	CreatedAt int64  `json:"createdAt,omitempty"`
 	UpdatedAt int64  `json:"updatedAt,omitempty"`
 	CreatedBy string `json:"createdBy,omitempty"`
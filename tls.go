@@ -0,0 +1,183 @@
+package sql
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gocql/gocql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+)
+
+// TLSMode is how the server certificate is checked, the values follow the sslmode of postgres
+type TLSMode string
+
+const (
+	TLSModeDisable TLSMode = "disable"
+	// TLSModeRequire encrypts the connection without checking the server certificate
+	TLSModeRequire TLSMode = "require"
+	// TLSModeVerifyCA checks that the server certificate is signed by the CA but not its host name
+	TLSModeVerifyCA TLSMode = "verify-ca"
+	// TLSModeVerifyFull checks the CA and that the certificate is for ServerName, or the host when it is empty
+	TLSModeVerifyFull TLSMode = "verify-full"
+)
+
+// TLSConfig are the files and the mode of a TLS connection, with Cert and Key the client is authenticated too
+type TLSConfig struct {
+	// CA is the path of the PEM certificates of the CAs, the system CAs are used when it is empty
+	CA string `json:"ca"`
+	// Cert and Key are the paths of the PEM client certificate and its key
+	Cert       string  `json:"cert"`
+	Key        string  `json:"key"`
+	ServerName string  `json:"serverName"`
+	Mode       TLSMode `json:"mode"`
+}
+
+// Enabled is true when the mode is set and is not disable
+func (c TLSConfig) Enabled() bool {
+	return c.Mode != "" && c.Mode != TLSModeDisable
+}
+
+func (c TLSConfig) validate() error {
+	switch c.Mode {
+	case "", TLSModeDisable, TLSModeRequire, TLSModeVerifyCA, TLSModeVerifyFull:
+	default:
+		return fmt.Errorf("tls mode %q is not supported", c.Mode)
+	}
+	if (c.Cert == "") != (c.Key == "") {
+		return errors.New("tls cert and key must be set together")
+	}
+	return nil
+}
+
+// Config reads the certificates and builds the tls.Config for the mode
+func (c TLSConfig) Config() (*tls.Config, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: c.ServerName,
+	}
+
+	if c.CA != "" {
+		b, err := os.ReadFile(c.CA)
+		if err != nil {
+			return nil, fmt.Errorf("tls ca: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("tls ca: no certificates in %s", c.CA)
+		}
+	}
+	if c.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(c.Cert, c.Key)
+		if err != nil {
+			return nil, fmt.Errorf("tls cert: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	switch c.Mode {
+	case TLSModeRequire:
+		cfg.InsecureSkipVerify = true
+	case TLSModeVerifyCA:
+		// The chain is verified without the host name, which the default verification can't skip
+		cfg.InsecureSkipVerify = true
+		roots := cfg.RootCAs
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("tls: no server certificate")
+			}
+			opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+			for _, cert := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := cs.PeerCertificates[0].Verify(opts)
+			return err
+		}
+	}
+	return cfg, nil
+}
+
+// mysqlConfigName is the name the config is registered with the mysql driver, it is the same for the same config
+func (c TLSConfig) mysqlConfigName() string {
+	b, _ := json.Marshal(c)
+	sum := sha256.Sum256(b)
+	return "go-sql-" + hex.EncodeToString(sum[:8])
+}
+
+// postgresParams are the libpq parameters of the config
+func (c TLSConfig) postgresParams() string {
+	v := url.Values{}
+	v.Set("sslmode", string(c.Mode))
+	if c.CA != "" {
+		v.Set("sslrootcert", c.CA)
+	}
+	if c.Cert != "" {
+		v.Set("sslcert", c.Cert)
+		v.Set("sslkey", c.Key)
+	}
+	return v.Encode()
+}
+
+// registerMySQLTLS registers the tls.Config that the tls parameter of getMysqlDataSource refers to
+func (o *DB) registerMySQLTLS() error {
+	cfg, err := o.TLS.Config()
+	if err != nil {
+		return err
+	}
+	return mysql.RegisterTLSConfig(o.TLS.mysqlConfigName(), cfg)
+}
+
+// openPostgresTLS opens the pool with pgx so the tls.Config, ie. its ServerName, is used as it is.
+// Only the TLS connections use the pgx driver through pgx/stdlib, the others use lib/pq,
+// sqlx binds both with $n placeholders.
+func (o *DB) openPostgresTLS(dataSource string) (*sqlx.DB, error) {
+	connConfig, err := o.postgresTLSConnConfig(dataSource)
+	if err != nil {
+		return nil, err
+	}
+	return sqlx.NewDb(stdlib.OpenDB(*connConfig), o.DBSource.String()), nil
+}
+
+// postgresTLSConnConfig replaces the tls.Config that pgx builds from the sslmode, verify-full checks
+// the certificate against the host when the ServerName is not set
+func (o *DB) postgresTLSConnConfig(dataSource string) (*pgx.ConnConfig, error) {
+	cfg, err := o.TLS.Config()
+	if err != nil {
+		return nil, err
+	}
+	connConfig, err := pgx.ParseConfig(dataSource)
+	if err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = connConfig.Host
+	}
+	connConfig.TLSConfig = cfg
+	connConfig.Fallbacks = nil
+	return connConfig, nil
+}
+
+// cqlSslOptions verifies the host unless the mode skips it, gocql sets the ServerName of each host
+func (o *DB) cqlSslOptions() (*gocql.SslOptions, error) {
+	cfg, err := o.TLS.Config()
+	if err != nil {
+		return nil, err
+	}
+	return &gocql.SslOptions{
+		Config:                 cfg,
+		CaPath:                 o.CaPath,
+		EnableHostVerification: !cfg.InsecureSkipVerify,
+	}, nil
+}
@@ -0,0 +1,212 @@
+package sql
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a self signed certificate for localhost and its key to dir
+func writeTestCert(t *testing.T, dir string) (certPath, keyPath string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPath, keyPath = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certPath, keyPath, cert
+}
+
+func TestTLSConfig_Config(t *testing.T) {
+	certPath, keyPath, cert := writeTestCert(t, t.TempDir())
+	other, _, _ := writeTestCert(t, t.TempDir())
+
+	tests := []struct {
+		name         string
+		config       TLSConfig
+		wantErr      bool
+		wantInsecure bool
+		// verify calls VerifyConnection with the test certificate
+		verify        bool
+		wantVerifyErr bool
+	}{
+		{
+			name:   "should pass; verify full with a client certificate",
+			config: TLSConfig{CA: certPath, Cert: certPath, Key: keyPath, Mode: TLSModeVerifyFull},
+		},
+		{
+			name:         "should pass; require skips the verification",
+			config:       TLSConfig{Mode: TLSModeRequire},
+			wantInsecure: true,
+		},
+		{
+			name:         "should pass; verify ca",
+			config:       TLSConfig{CA: certPath, Mode: TLSModeVerifyCA},
+			wantInsecure: true,
+			verify:       true,
+		},
+		{
+			name:          "should fail verification; verify ca with another ca",
+			config:        TLSConfig{CA: other, Mode: TLSModeVerifyCA},
+			wantInsecure:  true,
+			verify:        true,
+			wantVerifyErr: true,
+		},
+		{
+			name:    "should fail; cert without key",
+			config:  TLSConfig{Cert: certPath, Mode: TLSModeVerifyFull},
+			wantErr: true,
+		},
+		{
+			name:    "should fail; unknown mode",
+			config:  TLSConfig{Mode: "prefer"},
+			wantErr: true,
+		},
+		{
+			name:    "should fail; missing ca",
+			config:  TLSConfig{CA: filepath.Join(t.TempDir(), "missing.pem"), Mode: TLSModeVerifyFull},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.config.Config()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TLSConfig.Config() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.InsecureSkipVerify != tt.wantInsecure {
+				t.Errorf("TLSConfig.Config().InsecureSkipVerify = %v, want %v", got.InsecureSkipVerify, tt.wantInsecure)
+			}
+			if tt.config.Cert != "" && len(got.Certificates) != 1 {
+				t.Errorf("TLSConfig.Config().Certificates = %d, want 1", len(got.Certificates))
+			}
+			if tt.verify {
+				err := got.VerifyConnection(tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}})
+				if (err != nil) != tt.wantVerifyErr {
+					t.Errorf("TLSConfig.Config().VerifyConnection() error = %v, wantErr %v", err, tt.wantVerifyErr)
+				}
+			}
+		})
+	}
+}
+
+func TestWithTLS(t *testing.T) {
+	got, err := NewConfig(WithTLS(TLSConfig{ServerName: "db.internal"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TLS.Mode != TLSModeVerifyFull || !got.TLS.Enabled() {
+		t.Errorf("WithTLS() mode = %q, want %q", got.TLS.Mode, TLSModeVerifyFull)
+	}
+	if _, err := NewConfig(WithTLS(TLSConfig{Mode: "allow"})); err == nil {
+		t.Error("WithTLS() with an unknown mode, want an error")
+	}
+}
+
+func TestDB_postgresTLSConnConfig(t *testing.T) {
+	certPath, keyPath, _ := writeTestCert(t, t.TempDir())
+	serverCert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{serverCert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	tests := []struct {
+		name           string
+		serverName     string
+		wantServerName string
+		wantErr        bool
+	}{
+		{name: "should pass; verify-full checks the host", wantServerName: "localhost"},
+		{name: "should pass; server name", serverName: "localhost", wantServerName: "localhost"},
+		{name: "should fail; server name of another host", serverName: "db.internal", wantServerName: "db.internal", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &DB{
+				DBSource: DBSource_postgres,
+				User:     "postgres",
+				Password: "postgres",
+				Hosts:    []string{"localhost"},
+				Port:     "5432",
+				DBName:   "test_db",
+				TLS:      TLSConfig{CA: certPath, ServerName: tt.serverName, Mode: TLSModeVerifyFull},
+			}
+			dataSource, err := o.getDataSource()
+			if err != nil {
+				t.Fatal(err)
+			}
+			connConfig, err := o.postgresTLSConnConfig(dataSource)
+			if err != nil {
+				t.Fatalf("DB.postgresTLSConnConfig() error = %v", err)
+			}
+			if got := connConfig.TLSConfig.ServerName; got != tt.wantServerName {
+				t.Errorf("DB.postgresTLSConnConfig() server name = %q, want %q", got, tt.wantServerName)
+			}
+
+			conn, err := tls.Dial("tcp", ln.Addr().String(), connConfig.TLSConfig)
+			if err == nil {
+				conn.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("handshake error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
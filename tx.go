@@ -0,0 +1,303 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/gocql/gocql"
+	"github.com/jmoiron/sqlx"
+	cqlreflectx "github.com/scylladb/go-reflectx"
+)
+
+var (
+	ErrTxDone = errors.New("transaction has already been committed or rolled back")
+)
+
+const defaultTxRetries = 3
+
+// TxOptions are the options used when starting a transaction
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// BatchType is only used by cql, it defaults to a logged batch
+	BatchType gocql.BatchType
+}
+
+// Tx is a transaction started from a DB.
+// For sql sources it wraps a sqlx transaction, for cql the writes are collected
+// into a batch that is executed when the transaction is committed.
+// Reads on a cql Tx go straight to the session and will not see the pending writes.
+type Tx struct {
+	db    *DB
+	sql   *sqlx.Tx
+	batch *gocql.Batch
+	done  bool
+}
+
+// BeginTx starts a transaction, if opts is nil the defaults are used
+func (o *DB) BeginTx(ctx context.Context, opts *TxOptions) (*Tx, error) {
+	if opts == nil {
+		opts = &TxOptions{BatchType: gocql.LoggedBatch}
+	}
+
+	if o.cql != nil {
+		return &Tx{
+			db:    o,
+			batch: o.cql.Session.NewBatch(opts.BatchType).WithContext(ctx),
+		}, nil
+	}
+
+	if o.sql != nil {
+		tx, err := o.sql.BeginTxx(ctx, &sql.TxOptions{
+			Isolation: opts.Isolation,
+			ReadOnly:  opts.ReadOnly,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("begin tx: %w", err)
+		}
+		return &Tx{db: o, sql: tx}, nil
+	}
+
+	return nil, ErrNoSourceConfigured
+}
+
+// RunInTx runs fn inside of a transaction. The transaction is committed if fn returns nil,
+// and rolled back if fn returns an error or panics.
+// Serialization failures and deadlocks are retried up to TxRetries times.
+func (o *DB) RunInTx(ctx context.Context, fn func(*Tx) error) error {
+	var err error
+	for attempt := 0; attempt <= o.TxRetries; attempt++ {
+		if attempt > 0 {
+			o.Debugf("run in tx: retry %d: %v", attempt, err)
+		}
+		err = o.runInTx(ctx, fn)
+		if err == nil || !isSerializationFailure(err) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+func (o *DB) runInTx(ctx context.Context, fn func(*Tx) error) error {
+	tx, err := o.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		// database/sql rolls back the transaction itself when ctx is canceled
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			return fmt.Errorf("rollback: %v: %w", rbErr, err)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Commit commits the sql transaction or executes the cql batch
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+
+	if tx.batch != nil {
+		if tx.batch.Size() == 0 {
+			return nil
+		}
+		if err := tx.db.cql.Session.ExecuteBatch(tx.batch); err != nil {
+			return fmt.Errorf("execute batch: %w", err)
+		}
+		return nil
+	}
+
+	if tx.sql != nil {
+		if err := tx.sql.Commit(); err != nil {
+			return fmt.Errorf("commit transaction: %w", err)
+		}
+		return nil
+	}
+
+	return ErrNoSourceConfigured
+}
+
+// Rollback aborts the sql transaction or discards the pending cql batch
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+
+	if tx.batch != nil {
+		tx.batch.Entries = nil
+		return nil
+	}
+
+	if tx.sql != nil {
+		return tx.sql.Rollback()
+	}
+
+	return ErrNoSourceConfigured
+}
+
+func (tx *Tx) Select(dst interface{}, stmt string, names []string, args interface{}) error {
+	return tx.SelectContext(context.Background(), dst, stmt, names, args)
+}
+
+// SelectContext reads outside of the batch for cql, the statements of the batch are not visible before Commit
+func (tx *Tx) SelectContext(ctx context.Context, dst interface{}, stmt string, names []string, args interface{}) error {
+	if tx.batch != nil {
+		return tx.db.SelectContext(ctx, dst, stmt, names, args)
+	}
+
+	if tx.sql != nil {
+		query, err := tx.prepareNamed(ctx, stmt, names)
+		if err != nil {
+			return fmt.Errorf("prepare named: %w", err)
+		}
+		defer query.Close()
+		return query.SelectContext(ctx, dst, args)
+	}
+
+	return ErrNoSourceConfigured
+}
+
+// Returns one document
+func (tx *Tx) Get(dst interface{}, stmt string, names []string, args interface{}) error {
+	return tx.GetContext(context.Background(), dst, stmt, names, args)
+}
+
+// GetContext reads outside of the batch for cql like SelectContext
+func (tx *Tx) GetContext(ctx context.Context, dst interface{}, stmt string, names []string, args interface{}) error {
+	if tx.batch != nil {
+		return tx.db.GetContext(ctx, dst, stmt, names, args)
+	}
+
+	if tx.sql != nil {
+		query, err := tx.prepareNamed(ctx, stmt, names)
+		if err != nil {
+			return fmt.Errorf("prepare named: %w", err)
+		}
+		defer query.Close()
+		return query.GetContext(ctx, dst, args)
+	}
+
+	return ErrNoSourceConfigured
+}
+
+// prepareNamed prepares on the connection of the transaction, the statement cache of the DB
+// prepares on the pool which could wait for the connection held by the transaction
+func (tx *Tx) prepareNamed(ctx context.Context, stmt string, names []string) (*sqlx.NamedStmt, error) {
+	query, err := CompileNamedStatement(tx.db.DBSource, stmt, names)
+	if err != nil {
+		return nil, err
+	}
+	return tx.sql.PrepareNamedContext(ctx, query)
+}
+
+func (tx *Tx) Exec(stmt string, names []string, args interface{}) error {
+	return tx.ExecContext(context.Background(), stmt, names, args)
+}
+
+// ExecContext adds the statement to the batch for cql, it only runs on Commit
+func (tx *Tx) ExecContext(ctx context.Context, stmt string, names []string, args interface{}) error {
+	if tx.done {
+		return ErrTxDone
+	}
+
+	if tx.batch != nil {
+		values, err := bindCQLArgs(tx.db.cql.Mapper, names, args)
+		if err != nil {
+			return fmt.Errorf("bind: %w", err)
+		}
+		tx.batch.Query(stmt, values...)
+		return nil
+	}
+
+	if tx.sql != nil {
+		namedStmt, err := CompileNamedStatement(tx.db.DBSource, stmt, names)
+		if err != nil {
+			return fmt.Errorf("named statement: %w", err)
+		}
+		_, err = tx.sql.NamedExecContext(ctx, namedStmt, args)
+		return err
+	}
+
+	return ErrNoSourceConfigured
+}
+
+func (tx *Tx) ExecMap(stmt string, names []string, args map[string]interface{}) error {
+	return tx.ExecMapContext(context.Background(), stmt, names, args)
+}
+
+// ExecMapContext is ExecContext with a map
+func (tx *Tx) ExecMapContext(ctx context.Context, stmt string, names []string, args map[string]interface{}) error {
+	return tx.ExecContext(ctx, stmt, names, args)
+}
+
+// bindCQLArgs resolves the values for names from either a map or a struct
+func bindCQLArgs(mapper *cqlreflectx.Mapper, names []string, arg interface{}) ([]interface{}, error) {
+	out := make([]interface{}, 0, len(names))
+
+	if m, ok := arg.(map[string]interface{}); ok {
+		for _, name := range names {
+			val, ok := m[name]
+			if !ok {
+				return nil, fmt.Errorf("could not find name %q in map", name)
+			}
+			out = append(out, val)
+		}
+		return out, nil
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(arg))
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected a struct or map, got %T", arg)
+	}
+
+	tm := mapper.TypeMap(v.Type())
+	for _, name := range names {
+		fi, ok := tm.Names[name]
+		if !ok {
+			return nil, fmt.Errorf("could not find name %q in %T", name, arg)
+		}
+		out = append(out, cqlreflectx.FieldByIndexesReadOnly(v, fi.Index).Interface())
+	}
+	return out, nil
+}
+
+// isSerializationFailure reports if a transaction failed because of a conflict with another transaction
+// and can be retried
+func isSerializationFailure(err error) bool {
+	// postgres: serialization_failure and deadlock_detected
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		switch pgErr.SQLState() {
+		case "40001", "40P01":
+			return true
+		}
+	}
+
+	// mysql: ER_LOCK_DEADLOCK
+	var myErr *mysqldriver.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == 1213
+	}
+
+	return false
+}
@@ -0,0 +1,111 @@
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/google/go-cmp/cmp"
+	"github.com/joematpal/go-sql/v2/table"
+	cqlreflectx "github.com/scylladb/go-reflectx"
+)
+
+// sqlStateError is a postgres error of pgx or lib/pq, they both have SQLState
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func Test_isSerializationFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "should pass; postgres serialization failure",
+			err:  fmt.Errorf("commit transaction: %w", sqlStateError("40001")),
+			want: true,
+		},
+		{
+			name: "should pass; postgres deadlock",
+			err:  sqlStateError("40P01"),
+			want: true,
+		},
+		{
+			name: "should pass; postgres unique violation",
+			err:  sqlStateError("23505"),
+			want: false,
+		},
+		{
+			name: "should pass; mysql deadlock",
+			err:  fmt.Errorf("exec: %w", &mysqldriver.MySQLError{Number: 1213}),
+			want: true,
+		},
+		{
+			name: "should pass; generic error",
+			err:  errors.New("boom"),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSerializationFailure(tt.err); got != tt.want {
+				t.Errorf("isSerializationFailure() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_bindCQLArgs(t *testing.T) {
+	type user struct {
+		UserID   string `json:"userId"`
+		Username string `json:"username"`
+	}
+	mapper := cqlreflectx.NewMapperTagFunc("json", table.PreMapFunc(cqlreflectx.CamelToSnakeASCII), table.PreMapFunc(cqlreflectx.CamelToSnakeASCII))
+
+	tests := []struct {
+		name    string
+		names   []string
+		arg     interface{}
+		want    []interface{}
+		wantErr bool
+	}{
+		{
+			name:  "should pass; struct",
+			names: []string{"username", "user_id"},
+			arg:   &user{UserID: "1", Username: "test"},
+			want:  []interface{}{"test", "1"},
+		},
+		{
+			name:  "should pass; map",
+			names: []string{"user_id"},
+			arg:   map[string]interface{}{"user_id": "1"},
+			want:  []interface{}{"1"},
+		},
+		{
+			name:    "should fail; missing field",
+			names:   []string{"email"},
+			arg:     user{},
+			wantErr: true,
+		},
+		{
+			name:    "should fail; not a struct",
+			names:   []string{"user_id"},
+			arg:     "1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bindCQLArgs(mapper, tt.names, tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("bindCQLArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !cmp.Equal(got, tt.want) {
+				t.Error(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}